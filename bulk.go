@@ -106,7 +106,7 @@ func (m BulkInsert) Errors() error {
 }
 
 func (m *BulkInsert) doInsert() {
-	query, params := m.insert.SQL()
+	query, params := m.insert.SQL(identQuote(MustGetDB(m.ctx)))
 	var err error
 	if len(m.insert.returning) > 0 {
 		err = Select(m.ctx, &m.returned, query, params...)
@@ -138,11 +138,12 @@ func (b *biBuilder) values(vals ...any) {
 	b.vals = append(b.vals, vals)
 }
 
-func (b *biBuilder) SQL(vals ...string) (string, []any) {
+// SQL builds the query, quoting the table name with q.
+func (b *biBuilder) SQL(q byte) (string, []any) {
 	var s strings.Builder
-	s.WriteString(`insert into "`)
-	s.WriteString(b.table)
-	s.WriteString(`" (`)
+	s.WriteString(`insert into `)
+	s.WriteString(quoteIdent(q, b.table))
+	s.WriteString(` (`)
 
 	s.WriteString(strings.Join(b.cols, ","))
 	s.WriteString(") values ")
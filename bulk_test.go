@@ -14,10 +14,14 @@ func TestBuilder(t *testing.T) {
 	want := `insert into "TBL" (col1,col2,col3) values (?,?,?),(?,?,?) returning r1,r2`
 	wantargs := []any{"one", "two", "three", "a", "b", "c"}
 
-	query, args := b.SQL()
+	query, args := b.SQL('"')
 	if query != want {
 		t.Errorf("wrong query\nwant: %q\ngot:  %q", want, query)
 	}
+	want = "insert into `TBL` (col1,col2,col3) values (?,?,?),(?,?,?) returning r1,r2"
+	if query, _ := b.SQL('`'); query != want {
+		t.Errorf("wrong query\nwant: %q\ngot:  %q", want, query)
+	}
 	if !reflect.DeepEqual(args, wantargs) {
 		t.Errorf("wrong args\nwant: %q\ngot:  %q", wantargs, args)
 	}
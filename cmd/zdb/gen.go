@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"zgo.at/zdb"
+)
+
+const genStructsHelp = `usage: zdb gen-structs [flags]
+
+Generate Go structs for all tables; see zdb.GenerateStructs().
+
+This is intended to be used with go generate; for example, to generate the
+structs from db/schema.sql in an in-memory SQLite database:
+
+    //go:generate go run zgo.at/zdb/cmd/zdb gen-structs -db sqlite3+:memory: -files ./db -o tables.go
+
+Flags:
+    -db       Connect string.
+    -files    Directory with database files.
+    -o        Output file; default is stdout.
+    -pkg      Package name; default is $GOPACKAGE, as set by go generate.
+    -tables   Comma-separated list of tables; default is all tables.
+    -views    Also generate structs for views.
+`
+
+const genQueriesHelp = `usage: zdb gen-queries [flags]
+
+Generate typed Go functions for all queries in the query directory; see
+zdb.GenerateQueries().
+
+This is intended to be used with go generate; for example:
+
+    //go:generate go run zgo.at/zdb/cmd/zdb gen-queries -db sqlite3+:memory: -files ./db -o queries.go
+
+Flags:
+    -db        Connect string.
+    -files     Directory with database files; this is required.
+    -o         Output file; default is stdout.
+    -pkg       Package name; default is $GOPACKAGE, as set by go generate.
+    -queries   Comma-separated list of queries; default is all queries.
+`
+
+func genStructs(ctx context.Context, f *flag.FlagSet, args []string) error {
+	var (
+		conn, files = dbFlags(f)
+		out         = f.String("o", "", "Output file")
+		pkg         = f.String("pkg", os.Getenv("GOPACKAGE"), "Package name")
+		tables      = f.String("tables", "", "Tables to generate")
+		views       = f.Bool("views", false, "Also generate views")
+	)
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *pkg == "" {
+		return errors.New("-pkg is required outside of go generate")
+	}
+
+	ctx, close, err := connect(ctx, *conn, *files, true)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	opt := zdb.GenerateOptions{Package: *pkg, Views: *views}
+	if *tables != "" {
+		opt.Tables = strings.Split(*tables, ",")
+	}
+	src, err := zdb.GenerateStructs(ctx, opt)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, src)
+}
+
+func genQueries(ctx context.Context, f *flag.FlagSet, args []string) error {
+	var (
+		conn, files = dbFlags(f)
+		out         = f.String("o", "", "Output file")
+		pkg         = f.String("pkg", os.Getenv("GOPACKAGE"), "Package name")
+		queries     = f.String("queries", "", "Queries to generate")
+	)
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *pkg == "" {
+		return errors.New("-pkg is required outside of go generate")
+	}
+	if *files == "" {
+		return errors.New("-files is required")
+	}
+
+	ctx, close, err := connect(ctx, *conn, *files, true)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	opt := zdb.GenerateOptions{
+		Package: *pkg,
+		Skipped: func(q string, err error) { fmt.Fprintf(os.Stderr, "zdb gen-queries: skipping %q: %s\n", q, err) },
+	}
+	if *queries != "" {
+		opt.Queries = strings.Split(*queries, ",")
+	}
+	src, err := zdb.GenerateQueries(ctx, opt)
+	if err != nil {
+		return err
+	}
+	return writeOutput(*out, src)
+}
+
+// writeOutput writes to the file out, or stdout if it's empty.
+func writeOutput(out string, src []byte) error {
+	if out == "" {
+		_, err := os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
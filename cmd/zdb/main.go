@@ -0,0 +1,133 @@
+// Command zdb works with zdb databases.
+//
+// Commands:
+//
+//	migrate        Show, run, or roll back migrations.
+//	create         Create the database schema.
+//	query          Run a query and show the result.
+//	explain        Show the query plan for a query.
+//	shell          Interactive SQL shell.
+//	gen-structs    Generate Go structs for all tables.
+//	gen-queries    Generate typed Go functions for all queries.
+//
+// All commands accept the same connect string as zdb.Connect() with -db, and a
+// directory with the database files (schema, migrations, queries) with -files.
+// Use "zdb help [command]" for the flags of a command.
+//
+// The go-sqlite3, pgx, and mariadb drivers are included.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"zgo.at/zdb"
+	_ "zgo.at/zdb-drivers/go-sqlite3"
+	_ "zgo.at/zdb-drivers/mariadb"
+	_ "zgo.at/zdb-drivers/pgx"
+)
+
+const usage = `usage: zdb command [flags]
+
+Commands:
+    migrate        Show, run, or roll back migrations.
+    create         Create the database schema.
+    query          Run a query and show the result.
+    explain        Show the query plan for a query.
+    shell          Interactive SQL shell.
+    gen-structs    Generate Go structs for all tables.
+    gen-queries    Generate typed Go functions for all queries.
+    help           Show help; use "help command" for the flags of a command.
+
+All commands accept -db to set the connect string, and -files to set the
+directory with the database files (schema, migrations, queries).
+`
+
+type command struct {
+	help string
+	run  func(context.Context, *flag.FlagSet, []string) error
+}
+
+var commands = map[string]command{
+	"migrate":     {migrateHelp, migrate},
+	"create":      {createHelp, create},
+	"query":       {queryHelp, query},
+	"explain":     {explainHelp, explain},
+	"shell":       {shellHelp, shell},
+	"gen-structs": {genStructsHelp, genStructs},
+	"gen-queries": {genQueriesHelp, genQueries},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(1)
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+	switch name {
+	case "help", "-h", "-help", "--help":
+		if c, ok := commands[strings.Join(args, " ")]; ok {
+			fmt.Print(c.help)
+		} else {
+			fmt.Print(usage)
+		}
+		return
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "zdb: unknown command %q\n\n%s", name, usage)
+		os.Exit(1)
+	}
+
+	f := flag.NewFlagSet("zdb "+name, flag.ContinueOnError)
+	f.Usage = func() { fmt.Fprint(f.Output(), cmd.help) }
+	err := cmd.run(context.Background(), f, args)
+	if err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "zdb %s: %s\n", name, err)
+		}
+		os.Exit(1)
+	}
+}
+
+// dbFlags adds the -db and -files flags.
+func dbFlags(f *flag.FlagSet) (connect, files *string) {
+	return f.String("db", "", "Connect string; same as zdb.Connect()"),
+		f.String("files", "", "Directory with database files")
+}
+
+// connect to the database; with create the schema is created if the database
+// is empty. migrate are the migrations to run, as in zdb.ConnectOptions.
+func connect(ctx context.Context, conn, files string, create bool, migrate ...string) (context.Context, func(), error) {
+	if conn == "" {
+		return nil, nil, errors.New("-db is required")
+	}
+	var fsys fs.FS
+	if files != "" {
+		fsys = os.DirFS(files)
+	}
+	// Use a single connection, as every connection to an in-memory SQLite
+	// database gets its own empty database.
+	db, err := zdb.Connect(ctx, zdb.ConnectOptions{
+		Connect:      conn,
+		Create:       create,
+		Migrate:      migrate,
+		Files:        fsys,
+		MaxOpenConns: 1,
+		MaxIdleConns: 1,
+	})
+	if err != nil {
+		if db != nil {
+			db.Close()
+		}
+		return nil, nil, err
+	}
+	return zdb.WithDB(ctx, db), func() { db.Close() }, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"zgo.at/zdb"
+)
+
+const migrateHelp = `usage: zdb migrate [flags] [status|run|rollback|show] [name ...]
+
+Show, run, or roll back migrations; see zdb.Migrate.
+
+    status            Show which migrations have been run; this is the default.
+    run [name ...]    Run the migrations, or all pending migrations if no
+                      names are given.
+    rollback [name]   Roll back the migration, or the last migration if no
+                      name is given. This runs migrate/down/[name].sql.
+    show [name ...]   Show the SQL for the migrations, or all pending
+                      migrations if no names are given.
+
+Flags:
+    -db       Connect string.
+    -files    Directory with database files; this is required.
+    -test     Run in a transaction, but don't commit.
+`
+
+func migrate(ctx context.Context, f *flag.FlagSet, args []string) error {
+	var (
+		conn, files = dbFlags(f)
+		test        = f.Bool("test", false, "Don't commit")
+	)
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *files == "" {
+		return errors.New("-files is required")
+	}
+
+	ctx, close, err := connect(ctx, *conn, *files, false)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	m, err := zdb.NewMigrate(zdb.MustGetDB(ctx), os.DirFS(*files), nil)
+	if err != nil {
+		return err
+	}
+	m.Test(*test)
+	if cmd := f.Arg(0); cmd == "run" || cmd == "rollback" {
+		m.Log(func(name string) { fmt.Println(name) })
+	}
+
+	cmd, names := "status", f.Args()
+	if len(names) > 0 {
+		cmd, names = names[0], names[1:]
+	}
+
+	switch cmd {
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	case "status":
+		have, ran, err := m.List()
+		if err != nil {
+			return err
+		}
+		for _, h := range have {
+			if slices.Contains(ran, h) {
+				fmt.Printf("ran       %s\n", h)
+			} else {
+				fmt.Printf("pending   %s\n", h)
+			}
+		}
+		for _, r := range ran {
+			if !slices.Contains(have, r) {
+				fmt.Printf("missing   %s\n", r)
+			}
+		}
+		return nil
+	case "run":
+		if len(names) == 0 {
+			names = []string{"all"}
+		}
+		return m.Run(names...)
+	case "rollback":
+		if len(names) > 1 {
+			return errors.New("can only roll back one migration at a time")
+		}
+		name := ""
+		if len(names) == 1 {
+			name = names[0]
+		}
+		return m.Rollback(name)
+	case "show":
+		if len(names) == 0 {
+			err := m.Check()
+			var pending *zdb.PendingMigrationsError
+			if !errors.As(err, &pending) {
+				return err
+			}
+			names = pending.Pending
+		}
+		m.Show(true)
+		for _, n := range names {
+			err := m.Run(n)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
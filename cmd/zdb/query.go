@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"zgo.at/zdb"
+)
+
+const createHelp = `usage: zdb create [flags]
+
+Create the database schema from schema.sql if the database is new, and then
+run all migrations that aren't recorded as run in the version table. This is the
+same as zdb.Connect() with Create set. Go migrations can't be run from the
+command line.
+
+Flags:
+    -db       Connect string.
+    -files    Directory with database files; this is required.
+`
+
+const queryHelp = `usage: zdb query [flags] query [param ...]
+
+Run a query and show the result. The query can be "load:name" to load it from
+the query directory.
+
+Arguments after the query are positional parameters; use -p for named
+parameters.
+
+Flags:
+    -db       Connect string.
+    -files    Directory with database files.
+    -format   Output format: table (default), vertical, csv, json, or html.
+    -p        Named parameter as name=value; can be given more than once.
+`
+
+const explainHelp = `usage: zdb explain [flags] query [param ...]
+
+Show the query plan for a query; this is "explain analyze" on PostgreSQL, so
+the query is run.
+
+The query and parameters are the same as for zdb query.
+
+Flags:
+    -db       Connect string.
+    -files    Directory with database files.
+    -p        Named parameter as name=value; can be given more than once.
+`
+
+func create(ctx context.Context, f *flag.FlagSet, args []string) error {
+	conn, files := dbFlags(f)
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *files == "" {
+		return errors.New("-files is required")
+	}
+
+	_, close, err := connect(ctx, *conn, *files, true, "all")
+	if err != nil {
+		return err
+	}
+	close()
+	return nil
+}
+
+func query(ctx context.Context, f *flag.FlagSet, args []string) error {
+	var (
+		conn, files = dbFlags(f)
+		format      = f.String("format", "table", "Output format")
+		named       = namedFlag(f)
+	)
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	var dump zdb.DumpArg
+	switch *format {
+	case "table":
+		dump = zdb.DumpResult
+	case "vertical":
+		dump = zdb.DumpResult | zdb.DumpVertical
+	case "csv":
+		dump = zdb.DumpResult | zdb.DumpCSV
+	case "json":
+		dump = zdb.DumpResult | zdb.DumpJSON
+	case "html":
+		dump = zdb.DumpResult | zdb.DumpHTML
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	return dumpQuery(ctx, *conn, *files, f.Args(), named, dump)
+}
+
+func explain(ctx context.Context, f *flag.FlagSet, args []string) error {
+	var (
+		conn, files = dbFlags(f)
+		named       = namedFlag(f)
+	)
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+	return dumpQuery(ctx, *conn, *files, f.Args(), named, zdb.DumpExplain)
+}
+
+func dumpQuery(ctx context.Context, conn, files string, args []string, named map[string]any, dump zdb.DumpArg) error {
+	if len(args) == 0 {
+		return errors.New("need a query")
+	}
+
+	ctx, close, err := connect(ctx, conn, files, false)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	var params []any
+	for _, a := range args[1:] {
+		params = append(params, a)
+	}
+	if len(named) > 0 {
+		params = append(params, named)
+	}
+	return zdb.DumpErr(ctx, os.Stdout, args[0], append(params, dump)...)
+}
+
+// namedFlag adds the -p flag for named parameters.
+func namedFlag(f *flag.FlagSet) map[string]any {
+	named := make(map[string]any)
+	f.Func("p", "Named parameter as name=value", func(s string) error {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" {
+			return fmt.Errorf("not in the form name=value: %q", s)
+		}
+		named[k] = v
+		return nil
+	})
+	return named
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"zgo.at/zdb"
+)
+
+const shellHelp = `usage: zdb shell [flags]
+
+Start an interactive SQL shell; see zdb.Shell(). Use \? in the shell for help.
+
+Flags:
+    -db        Connect string.
+    -files     Directory with database files, for load: queries.
+    -history   History file; default is ~/.zdb_history. Set to "" to not
+               save history.
+`
+
+func shell(ctx context.Context, f *flag.FlagSet, args []string) error {
+	var hist string
+	if home, err := os.UserHomeDir(); err == nil {
+		hist = filepath.Join(home, ".zdb_history")
+	}
+
+	var (
+		conn, files = dbFlags(f)
+		history     = f.String("history", hist, "History file")
+	)
+	err := f.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, close, err := connect(ctx, *conn, *files, false)
+	if err != nil {
+		return err
+	}
+	defer close()
+
+	// Only show the prompt if stdin is a terminal, so that "zdb shell <file"
+	// works well.
+	var prompt string
+	if st, err := os.Stdin.Stat(); err == nil && st.Mode()&os.ModeCharDevice != 0 {
+		prompt = strings.ToLower(zdb.SQLDialect(ctx).String()) + "> "
+	}
+	return zdb.Shell(ctx, zdb.ShellOptions{Prompt: prompt, History: *history})
+}
@@ -0,0 +1,433 @@
+package zdb
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"zgo.at/zdb/internal/sqltoken"
+	"zgo.at/zdb/internal/sqlx"
+	"zgo.at/zdb/internal/sqlx/reflectx"
+)
+
+// condNode is a node in a parsed query with conditionals.
+type condNode struct {
+	text string // Plain text; only set if name is empty.
+	nq   int    // Number of ? placeholders in text.
+
+	name    string
+	negate  bool
+	then    []condNode
+	els     []condNode
+	hasElse bool
+}
+
+// countQ counts all ? placeholders in the nodes.
+func countQ(nodes []condNode) int {
+	var n int
+	for _, c := range nodes {
+		n += c.nq + countQ(c.then) + countQ(c.els)
+	}
+	return n
+}
+
+var condConfig = func() sqltoken.Config {
+	c := sqltoken.PostgreSQLConfig()
+	c.NoticeDollarNumber = false
+	return c
+}()
+
+// Delimiters for conditionals; templates use a different delimiter, as {{ is
+// already used by text/template.
+var (
+	condDelims    = [2]string{"{{:", "}}"}
+	tplCondDelims = [2]string{"{%:", "%}"}
+)
+
+type condParser struct {
+	q      string
+	pos    int
+	prot   [][2]int // Byte ranges of literals and comments.
+	delims [2]string
+}
+
+func newCondParser(query string, delims [2]string) *condParser {
+	var (
+		p   = &condParser{q: query, delims: delims}
+		off int
+	)
+	for _, t := range sqltoken.Tokenize(query, condConfig) {
+		if t.Type == sqltoken.Literal || t.Type == sqltoken.Comment || t.Type == sqltoken.Identifier {
+			p.prot = append(p.prot, [2]int{off, off + len(t.Text)})
+		}
+		off += len(t.Text)
+	}
+	return p
+}
+
+// parse until the end of the string, the closing delimiter, or "||" (if inElse
+// is false).
+func (p *condParser) parse(depth int, inElse bool) ([]condNode, string, error) {
+	var (
+		nodes  []condNode
+		start  = p.pos
+		nq     int
+		parens int
+		prot   int
+	)
+	flush := func() {
+		if p.pos > start {
+			nodes = append(nodes, condNode{text: p.q[start:p.pos], nq: nq})
+		}
+		nq = 0
+	}
+
+	for p.pos < len(p.q) {
+		for prot < len(p.prot) && p.prot[prot][1] <= p.pos {
+			prot++
+		}
+		if prot < len(p.prot) && p.prot[prot][0] <= p.pos {
+			p.pos = p.prot[prot][1]
+			continue
+		}
+
+		var (
+			rest          = p.q[p.pos:]
+			open, closing = p.delims[0], p.delims[1]
+		)
+		switch {
+		case strings.HasPrefix(rest, open):
+			n := strings.IndexAny(rest[len(open):], " \t\n")
+			if e := strings.Index(rest[len(open):], closing); n < 1 || (e > -1 && e < n) { // Not a conditional; leave it alone.
+				p.pos += len(open)
+				continue
+			}
+			flush()
+
+			c := condNode{name: rest[len(open) : len(open)+n]}
+			if strings.HasSuffix(c.name, "!") {
+				c.name, c.negate = c.name[:len(c.name)-1], true
+			}
+			p.pos += len(open) + n
+			var (
+				end string
+				err error
+			)
+			c.then, end, err = p.parse(depth+1, false)
+			if err != nil {
+				return nil, "", err
+			}
+			if end == "||" {
+				c.hasElse = true
+				c.els, end, err = p.parse(depth+1, true)
+				if err != nil {
+					return nil, "", err
+				}
+			}
+			if end != closing {
+				return nil, "", fmt.Errorf("unclosed conditional %q", c.name)
+			}
+			trimNodes(c.then)
+			trimNodes(c.els)
+			nodes = append(nodes, c)
+			start = p.pos
+
+		case depth > 0 && strings.HasPrefix(rest, closing):
+			flush()
+			p.pos += len(closing)
+			return nodes, closing, nil
+
+		case depth > 0 && !inElse && parens == 0 && strings.HasPrefix(rest, "||"):
+			flush()
+			p.pos += 2
+			return nodes, "||", nil
+
+		default:
+			switch rest[0] {
+			case '(':
+				parens++
+			case ')':
+				parens--
+			case '?':
+				nq++
+			}
+			p.pos++
+		}
+	}
+	flush()
+	return nodes, "", nil
+}
+
+// trimNodes removes leading whitespace from the first text node and trailing
+// whitespace from the last text node.
+func trimNodes(nodes []condNode) {
+	if len(nodes) == 0 {
+		return
+	}
+	if f := &nodes[0]; f.name == "" {
+		f.text = strings.TrimLeft(f.text, " \t\n")
+	}
+	if l := &nodes[len(nodes)-1]; l.name == "" {
+		l.text = strings.TrimRight(l.text, " \t\n")
+	}
+}
+
+type condRender struct {
+	b      []byte
+	skipWS bool
+
+	named     any   // map or struct
+	pos       []any // positional parameters; nil if named
+	qi        int   // Current ? index
+	keepPos   []any
+	modifyPos bool
+	unknown   []string // Named parameters that don't exist.
+}
+
+func (r *condRender) render(nodes []condNode) error {
+	for _, n := range nodes {
+		if n.name == "" {
+			r.write(n.text)
+			if r.modifyPos {
+				r.keepPos = append(r.keepPos, r.pos[r.qi:r.qi+n.nq]...)
+			}
+			r.qi += n.nq
+			continue
+		}
+
+		include, has, err := r.include(n.name)
+		if err != nil {
+			return err
+		}
+		if !has {
+			if !slices.Contains(r.unknown, n.name) {
+				r.unknown = append(r.unknown, n.name)
+			}
+			r.qi += countQ(n.then) + countQ(n.els)
+			continue
+		}
+		if n.negate {
+			include = !include
+		}
+
+		l := len(r.b)
+		if include {
+			err = r.render(n.then)
+			r.qi += countQ(n.els)
+		} else {
+			r.qi += countQ(n.then)
+			err = r.render(n.els)
+		}
+		if err != nil {
+			return err
+		}
+		if len(r.b) == l {
+			r.skipWS = true
+		}
+	}
+	return nil
+}
+
+func (r *condRender) include(name string) (bool, bool, error) {
+	if r.pos == nil {
+		// This is a bit inefficient, since it duplicates sqlx's NamedMapper
+		// logic; still seems plenty fast enough though.
+		return includeConditional(r.named, name)
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil {
+		return false, false, nil
+	}
+	if n < 1 || n > len(r.pos) {
+		return false, false, fmt.Errorf("conditional %q: no parameter %d", name, n)
+	}
+	include, err := isTruthy(name, r.pos[n-1])
+	return include, true, err
+}
+
+// write s, cleaning up the whitespace if the last conditional was removed.
+func (r *condRender) write(s string) {
+	if !r.skipWS {
+		r.b = append(r.b, s...)
+		return
+	}
+
+	ls := strings.TrimLeft(s, " \t")
+	if ls == "" { // Only whitespace: wait for the next text.
+		return
+	}
+	r.skipWS = false
+
+	var (
+		lineStart = strings.LastIndexByte(string(r.b), '\n') + 1
+		onLine    = strings.TrimLeft(string(r.b[lineStart:]), " \t")
+		last      byte
+	)
+	if len(r.b) > 0 {
+		last = r.b[len(r.b)-1]
+	}
+	switch {
+	case onLine == "" && lineStart > 0 && ls[0] == '\n': // Remove line.
+		r.b = r.b[:lineStart]
+		s = ls[1:]
+	case last == ' ' || last == '\t' || last == '\n' || last == '(':
+		s = ls
+		if ls[0] == ')' || ls[0] == ',' || ls[0] == ';' || ls[0] == '\n' {
+			r.b = []byte(strings.TrimRight(string(r.b), " \t"))
+		}
+	}
+	r.b = append(r.b, s...)
+}
+
+// Conditionals are in the form of:
+//
+//	{{:name text}}
+//	{{:name! text}}
+//	{{:name text || else text}}
+//
+// The text is included if the parameter "name" is true or not a zero value, and
+// "name!" negates this. The text after the first "||" that's not inside
+// parentheses is included if the condition is false; put the || operator inside
+// parentheses to use it in a conditional.
+//
+// Conditionals can be nested, and "{{:" inside string literals and comments is
+// ignored. It's an error if a named parameter doesn't exist, which includes
+// using a name when there are no parameters or only positional parameters. For
+// positional parameters the name is the parameter number, starting at 1, and
+// the parameters for "?" placeholders in text that's not included are removed.
+//
+// The whitespace around the conditional is cleaned up if nothing is included,
+// and a line with only the conditional on it is removed entirely, so that
+// the query text doesn't depend on how the query was formatted:
+//
+//	    where
+//	        {{:x x = :x}}
+//	    order by a
+//	→
+//	    where
+//	    order by a
+//
+// Templates use {%:name text%} instead.
+//
+// params is the result of prepareParams(); the parameters are returned, as the
+// positional parameters may be modified.
+func replaceConditionals(query string, params any, delims [2]string) (string, any, error) {
+	if !strings.Contains(query, delims[0]) {
+		return query, params, nil
+	}
+
+	p := newCondParser(query, delims)
+	nodes, _, err := p.parse(0, false)
+	if err != nil {
+		return "", nil, err
+	}
+
+	r := condRender{b: make([]byte, 0, len(query)), named: params}
+	if pos, ok := params.([]any); ok {
+		r.pos = pos
+		if r.pos == nil {
+			r.pos = []any{}
+		}
+		if n := countQ(nodes); n == len(pos) {
+			r.modifyPos, r.keepPos = true, make([]any, 0, len(pos))
+		} else if n > 0 {
+			return "", nil, fmt.Errorf("conditionals with positional parameters: %d ? placeholders but %d parameters", n, len(pos))
+		}
+	}
+	if params == nil {
+		r.pos = []any{}
+	}
+
+	err = r.render(nodes)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(r.unknown) > 0 {
+		if len(r.unknown) == 1 {
+			return "", nil, fmt.Errorf("unknown conditional parameter: %s", quoteList(r.unknown))
+		}
+		return "", nil, fmt.Errorf("unknown conditional parameters: %s", quoteList(r.unknown))
+	}
+	if r.skipWS {
+		r.b = []byte(strings.TrimRight(string(r.b), " \t"))
+	}
+
+	if r.modifyPos {
+		return string(r.b), r.keepPos, nil
+	}
+	return string(r.b), params, nil
+}
+
+// TODO: we can simplify this a bit if we just always convert struct to map in
+// prepareParams.
+func includeConditional(param any, name string) (include, has bool, err error) {
+	v := reflect.ValueOf(param)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	// Map
+	var m map[string]any
+	if v.Type().ConvertibleTo(reflect.TypeOf(m)) {
+		m = v.Convert(reflect.TypeOf(m)).Interface().(map[string]any)
+	}
+	if m != nil {
+		v, ok := m[name]
+		if !ok {
+			return false, false, nil
+		}
+		include, err := isTruthy(name, v)
+		return include, true, err
+	}
+
+	// Struct
+	if v.Kind() == reflect.Struct {
+		c := reflectx.NewMapper("db", sqlx.NameMapper).FieldByName(v, name)
+		if c.Type() == v.Type() { // FieldByName() returns original struct if it's not found.
+			return false, false, nil
+		}
+		include, err := isTruthy(name, c.Interface())
+		return include, true, err
+	}
+
+	return false, false, nil
+}
+
+func isTruthy(name string, cond any) (bool, error) {
+	if cond == nil {
+		return false, nil
+	}
+	t := reflect.TypeOf(cond)
+	v := reflect.ValueOf(cond)
+	switch t.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return false, nil
+		}
+		return isTruthy(name, v.Elem().Interface())
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.String, reflect.Array, reflect.Slice:
+		return v.Len() > 0, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() > 0, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint() > 0, nil
+	default:
+		switch c := cond.(type) {
+		case time.Time:
+			return !c.IsZero(), nil
+		}
+		return false, fmt.Errorf("unsupported conditional type %T for %q", cond, name)
+	}
+}
+
+func quoteList(l []string) string {
+	q := make([]string, 0, len(l))
+	for _, s := range l {
+		q = append(q, strconv.Quote(s))
+	}
+	return strings.Join(q, ", ")
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"slices"
 	"strings"
 
 	"zgo.at/zdb/drivers"
@@ -32,6 +33,16 @@ type ConnectOptions struct {
 	MaxOpenConns int
 	MaxIdleConns int
 
+	// Strict scanning mode for all queries; see [Strict].
+	Strict Strict
+
+	// Return an error from all queries with named parameters from a map that
+	// aren't used in the query. Parameters from structs are never reported,
+	// as it's common to pass a struct with more fields than needed.
+	//
+	// This is intended for tests and development.
+	LintParams bool
+
 	// In addition to migrations from .sql files, you can run migrations from Go
 	// functions. See the documentation on Migrate for details.
 	GoMigrations map[string]func(context.Context) error
@@ -136,6 +147,10 @@ func Connect(ctx context.Context, opt ConnectOptions) (DB, error) {
 		dialect:       dialect,
 		driverConn:    driverConn,
 		connectString: conn,
+		strict:        opt.Strict,
+		lint:          opt.LintParams,
+		quote:         '"',
+		tpl:           new(tplCache),
 	}
 
 	// These versions are required for zdb.
@@ -161,6 +176,19 @@ func Connect(ctx context.Context, opt ConnectOptions) (DB, error) {
 		return nil, err
 	}
 
+	// MariaDB treats "..." as a string literal unless ANSI_QUOTES is set, so
+	// quote identifiers with backticks.
+	if db.SQLDialect() == DialectMariaDB {
+		var mode string
+		err := Get(WithDB(context.Background(), db), &mode, `select @@sql_mode`)
+		if err != nil {
+			return nil, fmt.Errorf("zdb.Connect: getting sql_mode: %w", err)
+		}
+		if !slices.Contains(strings.Split(strings.ToUpper(mode), ","), "ANSI_QUOTES") {
+			db.quote = '`'
+		}
+	}
+
 	// No files for DB creation and migration: can just return now.
 	if opt.Files == nil {
 		return db, nil
@@ -0,0 +1,173 @@
+package zdb
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"zgo.at/zdb/internal/sqlx"
+)
+
+// Encoders registered with RegisterType(); this is copied on write, so reading
+// it doesn't need a lock.
+var (
+	encodersMu sync.Mutex
+	encoders   atomic.Pointer[map[reflect.Type]func(Dialect, any) (driver.Value, error)]
+)
+
+// RegisterType registers functions to convert T to and from database values.
+//
+// This allows using types from other packages as parameters and scan
+// destinations without wrapping them in a type that implements
+// [driver.Valuer] and [sql.Scanner]. For example, for netip.Addr:
+//
+//	zdb.RegisterType(
+//		func(d zdb.Dialect, a netip.Addr) (driver.Value, error) {
+//			return a.String(), nil
+//		},
+//		func(src any) (netip.Addr, error) {
+//			switch s := src.(type) {
+//			case string:
+//				return netip.ParseAddr(s)
+//			case []byte:
+//				return netip.ParseAddr(string(s))
+//			}
+//			return netip.Addr{}, fmt.Errorf("unsupported type: %T", src)
+//		})
+//
+// The encode function receives the dialect of the connection, so types can be
+// stored differently per database; for example a UUID as the native uuid type
+// on PostgreSQL and text on SQLite. Either function may be nil if only one
+// direction is needed.
+//
+// Registered types are used for parameters (T, *T, and slices of T with "in"),
+// and when scanning in to T or *T, including struct fields. NULL values are
+// never passed to decode; a *T is set to nil and T to the zero value. Values of
+// T are never treated as named parameters.
+//
+// This should be called before the types are used, typically in init().
+// Registering a type again replaces the previous functions.
+func RegisterType[T any](encode func(Dialect, T) (driver.Value, error), decode func(src any) (T, error)) {
+	t := reflect.TypeFor[T]()
+	if encode != nil {
+		encodersMu.Lock()
+		m := make(map[reflect.Type]func(Dialect, any) (driver.Value, error))
+		if cur := encoders.Load(); cur != nil {
+			for k, v := range *cur {
+				m[k] = v
+			}
+		}
+		m[t] = func(d Dialect, v any) (driver.Value, error) { return encode(d, v.(T)) }
+		encoders.Store(&m)
+		encodersMu.Unlock()
+	}
+	if decode != nil {
+		sqlx.RegisterDecoder(t, func(src any) (any, error) { return decode(src) })
+	}
+}
+
+// encoder gets the encoder for t, or nil if there is none.
+func encoder(t reflect.Type) func(Dialect, any) (driver.Value, error) {
+	m := encoders.Load()
+	if m == nil {
+		return nil
+	}
+	return (*m)[t]
+}
+
+// encodeParams converts all parameters with a type registered with
+// RegisterType().
+func encodeParams(dialect Dialect, params []any) ([]any, error) {
+	if encoders.Load() == nil {
+		return params, nil
+	}
+
+	// Copy on the first change, so the caller's slice isn't modified.
+	copied := false
+	set := func(i int, v any) {
+		if !copied {
+			params, copied = slices.Clone(params), true
+		}
+		params[i] = v
+	}
+	for i, p := range params {
+		if p == nil {
+			continue
+		}
+		v := reflect.ValueOf(p)
+		if enc := encoder(v.Type()); enc != nil {
+			e, err := enc(dialect, p)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+			}
+			set(i, e)
+			continue
+		}
+		if v.Kind() == reflect.Ptr {
+			if enc := encoder(v.Type().Elem()); enc != nil {
+				if v.IsNil() {
+					set(i, nil)
+					continue
+				}
+				e, err := enc(dialect, v.Elem().Interface())
+				if err != nil {
+					return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+				}
+				set(i, e)
+			}
+			continue
+		}
+
+		// Convert slices for "in (..)"; these are expanded later.
+		if v.Kind() == reflect.Slice {
+			if enc := encoder(v.Type().Elem()); enc != nil {
+				s := make([]any, v.Len())
+				for j := range s {
+					e, err := enc(dialect, v.Index(j).Interface())
+					if err != nil {
+						return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+					}
+					s[j] = e
+				}
+				set(i, s)
+			}
+		}
+	}
+	return params, nil
+}
+
+// jsonParam marshals v for a column with the ",json" option; nil pointers are
+// stored as NULL.
+func jsonParam(v any) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// jsonParams marshals all vals for which opts has ",json", as returned by
+// columnFields().
+func jsonParams(cols []string, vals []any, opts [][]string) error {
+	for i := range vals {
+		if !slices.Contains(opts[i], "json") {
+			continue
+		}
+		v, err := jsonParam(vals[i])
+		if err != nil {
+			return fmt.Errorf("column %q: %w", cols[i], err)
+		}
+		vals[i] = v
+	}
+	return nil
+}
@@ -0,0 +1,868 @@
+package zdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"zgo.at/zdb/internal/sqltoken"
+)
+
+// GenerateOptions are options for [GenerateStructs] and [GenerateQueries].
+type GenerateOptions struct {
+	// Package name for the generated file; this is required.
+	Package string
+
+	// Only generate structs for these tables; the default is all tables. Only
+	// used for GenerateStructs.
+	Tables []string
+
+	// Also generate structs for views; the Table() method is still added so
+	// they can be used with [Select] and the like, but you can't insert them.
+	// Only used for GenerateStructs.
+	Views bool
+
+	// Only generate functions for these queries; the default is all queries.
+	// Only used for GenerateQueries.
+	Queries []string
+
+	// Override the Go type for a database type. The keys are the type in lower
+	// case, and the values the Go type. For GenerateStructs this is the type as
+	// reported by [Columns] (e.g. "varchar(20)" or "jsonb"), and for
+	// GenerateQueries the type name from the driver (e.g. "varchar" or
+	// "int8"), or the type reported by Columns for a returning clause. Types
+	// from packages other than time need to be qualified and imported with
+	// Imports.
+	//
+	// These are used as-is for nullable columns; they're not made a pointer.
+	Types map[string]string
+
+	// Additional imports for types in Types.
+	Imports []string
+
+	// Called for queries that are skipped because the result columns can't be
+	// determined. The reason is also added as a comment in the output. Only
+	// used for GenerateQueries.
+	Skipped func(query string, err error)
+}
+
+// GenerateStructs generates Go structs for all tables in the database.
+//
+// Every struct implements [Tabler] and has a db tag for every column:
+//
+//   - an auto-incrementing primary key gets the ",id" option, so it's set by
+//     [Insert]. Other primary keys don't: Insert never inserts the ",id"
+//     column and requires it to be the zero value, so a natural key could
+//     never be inserted, and only one ",id" column is allowed, which rules out
+//     composite keys;
+//   - generated columns get the ",noinsert" option;
+//   - nullable columns are pointers, except for []byte;
+//   - numeric and decimal columns are a string, so no precision is lost; use
+//     Types to use a decimal type instead.
+//
+// The struct and field names are the table and column names in CamelCase, with
+// common initialisms such as ID and URL in upper case. The result is formatted
+// with gofmt. The version table used by [Migrate] is skipped.
+//
+// This is intended to be used with "go generate"; see the zdb command in
+// zgo.at/zdb/cmd/zdb.
+func GenerateStructs(ctx context.Context, opt GenerateOptions) ([]byte, error) {
+	if opt.Package == "" {
+		return nil, fmt.Errorf("zdb.GenerateStructs: Package is empty")
+	}
+
+	tables, err := Tables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("zdb.GenerateStructs: %w", err)
+	}
+	for _, t := range opt.Tables {
+		if !slices.ContainsFunc(tables, func(tt Table) bool { return tt.Name == t }) {
+			return nil, fmt.Errorf("zdb.GenerateStructs: no such table: %q", t)
+		}
+	}
+
+	var (
+		body    = new(bytes.Buffer)
+		imports = slices.Clone(opt.Imports)
+		dialect = SQLDialect(ctx)
+	)
+	for _, t := range tables {
+		if t.Name == "version" || (t.View && !opt.Views) {
+			continue
+		}
+		if opt.Tables != nil && !slices.Contains(opt.Tables, t.Name) {
+			continue
+		}
+
+		cols, err := Columns(ctx, t.Name)
+		if err != nil {
+			return nil, fmt.Errorf("zdb.GenerateStructs: %w", err)
+		}
+		npk := 0
+		for _, c := range cols {
+			if c.PrimaryKey {
+				npk++
+			}
+		}
+
+		name := goName(t.Name)
+		if t.View {
+			fmt.Fprintf(body, "// %s is a row in the %q view.\n", name, t.Name)
+		} else {
+			fmt.Fprintf(body, "// %s is a row in the %q table.\n", name, t.Name)
+		}
+		fmt.Fprintf(body, "type %s struct {\n", name)
+		for _, c := range cols {
+			typ, ok := opt.Types[c.Type]
+			if !ok {
+				typ = goType(dialect, c.Type)
+				if c.Nullable && typ != "[]byte" {
+					typ = "*" + typ
+				}
+			}
+			if strings.Contains(typ, "time.") && !slices.Contains(imports, "time") {
+				imports = append(imports, "time")
+			}
+
+			tag := c.Name
+			switch {
+			case c.PrimaryKey && c.AutoIncrement && npk == 1:
+				tag += ",id"
+			case c.Generated:
+				tag += ",noinsert"
+			}
+			fmt.Fprintf(body, "\t%s %s `db:%q`\n", goName(c.Name), typ, tag)
+		}
+		fmt.Fprintf(body, "}\n\n")
+		fmt.Fprintf(body, "func (%s) Table() string { return %q }\n\n", name, t.Name)
+	}
+
+	src, err := genFile(opt.Package, imports, body.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("zdb.GenerateStructs: %w", err)
+	}
+	return src, nil
+}
+
+// GenerateQueries generates typed Go functions for all queries in the query
+// directory (see [Load]).
+//
+// For every query it generates a function named after the query, a struct for
+// the parameters (if any), and a struct for the result rows. For example for
+// "select-sites.sql":
+//
+//	func SelectSites(ctx context.Context, p SelectSitesParams) ([]SelectSitesRow, error)
+//
+// Queries that don't return rows just return an error, and use [Exec].
+//
+// Named parameters (and conditionals) are found from the query source; for
+// templates this includes the parameters used in template actions and
+// partials. The type of a parameter is taken from the column it's compared to
+// with =, !=, <>, <, >, <=, >=, or like, assigned to in an update, or inserted
+// in with "insert into .. (cols) values (..)". Nullable columns are a pointer
+// for updates and inserts. The type is any if it's not used with a column, or
+// with columns of different types. A comment at the start of a line sets the
+// type explicitly:
+//
+//	-- :site_id int64
+//	-- :created time.Time
+//	select * from sites where site_id = :site_id and created_at > :created
+//
+// Queries are never run. The result columns of queries starting with select,
+// with, or values are found by running it as a subquery with "limit 0" and
+// NULL for all parameters. For an insert, update, or delete with a returning
+// clause the columns are looked up in the table; only column names (and *) are
+// supported in the returning clause, not expressions. Anything else is assumed
+// to not return any rows.
+//
+// Queries for which the columns can't be determined are skipped, with the
+// reason in a comment in the output and passed to Skipped if it's set.
+//
+// The field types are based on the type the driver reports and can be
+// overridden with Types; columns the driver reports as nullable are pointers.
+// Note that SQLite doesn't know the type of expressions, so they're always a
+// string, and that go-sqlite3 reports every column as nullable.
+func GenerateQueries(ctx context.Context, opt GenerateOptions) ([]byte, error) {
+	if opt.Package == "" {
+		return nil, fmt.Errorf("zdb.GenerateQueries: Package is empty")
+	}
+
+	db := MustGetDB(ctx)
+	qf, ok := Unwrap(db).(interface{ queryFiles() fs.FS })
+	if !ok || qf.queryFiles() == nil {
+		return nil, errors.New("zdb.GenerateQueries: Files not set")
+	}
+	fsys := qf.queryFiles()
+
+	names, err := queryNames(fsys)
+	if err != nil {
+		return nil, fmt.Errorf("zdb.GenerateQueries: %w", err)
+	}
+	for _, q := range opt.Queries {
+		if !slices.Contains(names, q) {
+			return nil, fmt.Errorf("zdb.GenerateQueries: no such query: %q", q)
+		}
+	}
+
+	var (
+		body    = new(bytes.Buffer)
+		imports = append(slices.Clone(opt.Imports), "context", "zgo.at/zdb")
+		dialect = SQLDialect(ctx)
+	)
+	for _, name := range names {
+		if opt.Queries != nil && !slices.Contains(opt.Queries, name) {
+			continue
+		}
+
+		src, file, err := findFile(fsys, insertDialect(db, name)...)
+		if err != nil {
+			return nil, fmt.Errorf("zdb.GenerateQueries: %w", err)
+		}
+		isTpl := strings.HasSuffix(file, ".gotxt")
+		if isTpl {
+			for _, m := range reTplPartial.FindAllSubmatch(src, -1) {
+				if p, err := fs.ReadFile(fsys, "_partials/"+string(m[1])+".gotxt"); err == nil {
+					src = append(append(src, '\n'), p...)
+				}
+			}
+		}
+		params, ptypes := queryParams(string(src), isTpl)
+		inferParamTypes(ctx, string(src), isTpl, opt.Types, ptypes)
+		cols, err := describeQuery(ctx, name, string(src), isTpl, params)
+		if err == nil {
+			err = dupeColumn(cols)
+		}
+		if err != nil {
+			fmt.Fprintf(body, "// Skipped %q: %s\n\n", name, strings.ReplaceAll(err.Error(), "\n", " "))
+			if opt.Skipped != nil {
+				opt.Skipped(name, err)
+			}
+			continue
+		}
+
+		fn := goName(name)
+		if len(params) > 0 {
+			fmt.Fprintf(body, "// %sParams are the parameters for the %q query.\n", fn, name)
+			fmt.Fprintf(body, "type %sParams struct {\n", fn)
+			for _, p := range params {
+				typ, ok := ptypes[p]
+				if !ok {
+					typ = "any"
+				}
+				fmt.Fprintf(body, "\t%s %s `db:%q`\n", goName(p), typ, p)
+			}
+			fmt.Fprintf(body, "}\n\n")
+		}
+		if len(cols) > 0 {
+			fmt.Fprintf(body, "// %sRow is a row returned by the %q query.\n", fn, name)
+			fmt.Fprintf(body, "type %sRow struct {\n", fn)
+			for _, c := range cols {
+				typ, ok := opt.Types[c.typ]
+				if !ok {
+					typ = goType(dialect, c.typ)
+					if c.nullable && typ != "[]byte" {
+						typ = "*" + typ
+					}
+				}
+				fmt.Fprintf(body, "\t%s %s `db:%q`\n", goName(c.name), typ, c.name)
+			}
+			fmt.Fprintf(body, "}\n\n")
+		}
+
+		var (
+			arg  = ""
+			pass = ""
+		)
+		if len(params) > 0 {
+			arg, pass = ", p "+fn+"Params", ", p"
+		}
+		fmt.Fprintf(body, "// %s runs the %q query.\n", fn, name)
+		if len(cols) > 0 {
+			fmt.Fprintf(body, "func %s(ctx context.Context%s) ([]%sRow, error) {\n", fn, arg, fn)
+			fmt.Fprintf(body, "\tvar rows []%sRow\n", fn)
+			fmt.Fprintf(body, "\terr := zdb.Select(ctx, &rows, %q%s)\n", "load:"+name, pass)
+			fmt.Fprintf(body, "\treturn rows, err\n}\n\n")
+		} else {
+			fmt.Fprintf(body, "func %s(ctx context.Context%s) error {\n", fn, arg)
+			fmt.Fprintf(body, "\treturn zdb.Exec(ctx, %q%s)\n}\n\n", "load:"+name, pass)
+		}
+	}
+	if strings.Contains(body.String(), "time.") {
+		imports = append(imports, "time")
+	}
+
+	src, err := genFile(opt.Package, imports, body.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("zdb.GenerateQueries: %w", err)
+	}
+	return src, nil
+}
+
+// genFile writes the header and imports, and formats the result.
+func genFile(pkg string, imports []string, body []byte) ([]byte, error) {
+	out := new(bytes.Buffer)
+	fmt.Fprintf(out, "// Code generated by zdb; DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	if len(imports) > 0 {
+		// Standard library first, like goimports.
+		isStd := func(imp string) bool { return !strings.Contains(strings.Split(imp, "/")[0], ".") }
+		slices.SortFunc(imports, func(a, b string) int {
+			if isStd(a) != isStd(b) {
+				if isStd(a) {
+					return -1
+				}
+				return 1
+			}
+			return strings.Compare(a, b)
+		})
+		imports = slices.Compact(imports)
+
+		fmt.Fprintf(out, "import (\n")
+		for i, imp := range imports {
+			if i > 0 && isStd(imports[i-1]) && !isStd(imp) {
+				fmt.Fprintf(out, "\n")
+			}
+			fmt.Fprintf(out, "\t%q\n", imp)
+		}
+		fmt.Fprintf(out, ")\n\n")
+	}
+	out.Write(body)
+	return format.Source(out.Bytes())
+}
+
+var dialectSuffix = regexp.MustCompile(`-(sqlite3?|postgres(ql)?|psql|maria(db)?|mysql)$`)
+
+// queryNames lists the names of all queries, without the extension or dialect;
+// partials and files ending in _test are skipped.
+func queryNames(fsys fs.FS) ([]string, error) {
+	ls, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, f := range ls {
+		ext := path.Ext(f.Name())
+		if f.IsDir() || (ext != ".sql" && ext != ".gotxt") {
+			continue
+		}
+		n := dialectSuffix.ReplaceAllString(strings.TrimSuffix(f.Name(), ext), "")
+		if !strings.HasSuffix(n, "_test") && !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	return names, nil
+}
+
+var (
+	reParamType  = regexp.MustCompile(`(?m)^\s*--\s*:(\w+)\s+(\S.*?)\s*$`)
+	reTplPartial = regexp.MustCompile(`\{\{-?\s*template\s+"([^"]+)"`)
+	reTplAction  = regexp.MustCompile(`\{\{[^:].*?\}\}`)
+	reTplParam   = regexp.MustCompile(`(?:^|[\s(])\.(\w+)`)
+)
+
+// queryParams gets the names of all named parameters, in order of appearance,
+// and their Go types. For templates this includes the parameters used in the
+// template actions.
+func queryParams(query string, isTpl bool) ([]string, map[string]string) {
+	var (
+		params []string
+		types  = make(map[string]string)
+		add    = func(p string) {
+			if !slices.Contains(params, p) {
+				params = append(params, p)
+			}
+		}
+	)
+	for _, t := range sqltoken.Tokenize(query, sqltoken.Config{NoticeColonWord: true}) {
+		if t.Type == sqltoken.ColonWord {
+			add(t.Text[1:])
+		}
+	}
+	if isTpl {
+		for _, a := range reTplAction.FindAllString(query, -1) {
+			for _, m := range reTplParam.FindAllStringSubmatch(a, -1) {
+				add(m[1])
+			}
+		}
+	}
+	for _, m := range reParamType.FindAllStringSubmatch(query, -1) {
+		if slices.Contains(params, m[1]) {
+			types[m[1]] = m[2]
+		}
+	}
+	return params, types
+}
+
+// inferParamTypes sets the type of parameters that don't have a type yet from
+// the column they're used with, if it's used as:
+//
+//	col = :p                                  Also for !=, <>, <, >, <=, >=, and like.
+//	:p = col
+//	update tbl set col = :p
+//	insert into tbl (col) values (:p)
+//
+// The column can be qualified with a table name, and is looked up in all tables
+// in the query (everything after from, join, into, and update). Nullable
+// columns are a pointer for insert and update, but not for comparisons.
+//
+// A parameter is left without a type if it's not used with a column, or if
+// it's used with columns of different types.
+func inferParamTypes(ctx context.Context, query string, isTpl bool, gotypes map[string]string, types map[string]string) {
+	if isTpl {
+		query = reTplAction.ReplaceAllString(query, "")
+	}
+	config := tokenConfig(SQLDialect(ctx))
+	config.NoticeColonWord = true
+
+	// Remove whitespace and comments, and split punctuation so that "(" and
+	// "," are always their own token, but keep comparison operators together.
+	var words sqltoken.Tokens
+	for _, t := range sqltoken.Tokenize(query, config) {
+		switch t.Type {
+		case sqltoken.Whitespace, sqltoken.Comment:
+		case sqltoken.Punctuation:
+			for i := 0; i < len(t.Text); i++ {
+				j := i + 1
+				for strings.IndexByte("<>=!", t.Text[i]) > -1 && j < len(t.Text) && strings.IndexByte("<>=!", t.Text[j]) > -1 {
+					j++
+				}
+				words = append(words, sqltoken.Token{Type: sqltoken.Punctuation, Text: t.Text[i:j]})
+				i = j - 1
+			}
+		default:
+			words = append(words, t)
+		}
+	}
+	isWord := func(i int, w string) bool {
+		return i >= 0 && i < len(words) && words[i].Type == sqltoken.Word && strings.EqualFold(words[i].Text, w)
+	}
+	isPunct := func(i int, p string) bool {
+		return i >= 0 && i < len(words) && words[i].Type == sqltoken.Punctuation && words[i].Text == p
+	}
+	isOp := func(i int) bool {
+		if isWord(i, "like") || isWord(i, "ilike") {
+			return true
+		}
+		return i >= 0 && i < len(words) && words[i].Type == sqltoken.Punctuation &&
+			slices.Contains([]string{"=", "!=", "<>", "<", ">", "<=", ">="}, words[i].Text)
+	}
+	// Column at i, optionally qualified with "tbl." before it.
+	column := func(i int) (tbl, col string, ok bool) {
+		if i < 0 || i >= len(words) || !isIdent(words[i]) {
+			return "", "", false
+		}
+		if isPunct(i-1, ".") && i >= 2 && isIdent(words[i-2]) {
+			tbl = unquoteIdent(words[i-2].Text)
+		}
+		return tbl, unquoteIdent(words[i].Text), true
+	}
+
+	// All tables in the query, keyed by name.
+	tables := make(map[string][]Column)
+	for i := range words {
+		if !isWord(i, "from") && !isWord(i, "join") && !isWord(i, "into") && !isWord(i, "update") {
+			continue
+		}
+		j := i + 1
+		if isWord(j, "only") {
+			j++
+		}
+		if isPunct(j+1, ".") {
+			j += 2
+		}
+		if j >= len(words) || !isIdent(words[j]) {
+			continue
+		}
+		name := unquoteIdent(words[j].Text)
+		if _, ok := tables[name]; ok {
+			continue
+		}
+		cols, err := Columns(ctx, name) // Not an error: can be a CTE or function.
+		if err == nil {
+			tables[name] = cols
+		}
+	}
+
+	var (
+		dialect = SQLDialect(ctx)
+		found   = make(map[string][]string)
+	)
+	use := func(param, tbl, col string, ptr bool) {
+		for name, cols := range tables {
+			if tbl != "" && tables[tbl] != nil && name != tbl {
+				continue
+			}
+			i := slices.IndexFunc(cols, func(c Column) bool { return strings.EqualFold(c.Name, col) })
+			if i == -1 {
+				continue
+			}
+			c := cols[i]
+			typ, ok := gotypes[c.Type]
+			if !ok {
+				typ = goType(dialect, c.Type)
+				if ptr && c.Nullable && typ != "[]byte" {
+					typ = "*" + typ
+				}
+			}
+			found[param] = append(found[param], typ)
+		}
+	}
+
+	var inSet bool
+	for i, t := range words {
+		switch {
+		case isWord(i, "set"):
+			inSet = true
+		case isWord(i, "where") || isWord(i, "from") || isWord(i, "returning"):
+			inSet = false
+
+		// insert into tbl (col, ...) values (:p, ...)
+		case isWord(i, "into") && isPunct(i+2, "("):
+			var cols []string
+			j := i + 3
+			for ; j < len(words) && !isPunct(j, ")"); j++ {
+				if isIdent(words[j]) {
+					cols = append(cols, unquoteIdent(words[j].Text))
+				}
+			}
+			tbl := unquoteIdent(words[i+1].Text)
+			for j++; isWord(j, "values") || isPunct(j, ","); j++ {
+				if !isPunct(j+1, "(") {
+					break
+				}
+				j += 2
+				for n := 0; j < len(words) && !isPunct(j, ")"); j++ {
+					switch {
+					case isPunct(j, ","):
+						n++
+					case words[j].Type == sqltoken.ColonWord && n < len(cols) && (isPunct(j-1, "(") || isPunct(j-1, ",")) &&
+						(isPunct(j+1, ")") || isPunct(j+1, ",")):
+						use(words[j].Text[1:], tbl, cols[n], true)
+					}
+				}
+			}
+
+		case t.Type == sqltoken.ColonWord:
+			if isOp(i - 1) {
+				if tbl, col, ok := column(i - 2); ok {
+					use(t.Text[1:], tbl, col, inSet && isPunct(i-1, "="))
+				}
+			}
+			if isOp(i + 1) {
+				j := i + 2
+				if isPunct(j+1, ".") {
+					j += 2
+				}
+				if tbl, col, ok := column(j); ok {
+					use(t.Text[1:], tbl, col, false)
+				}
+			}
+		}
+	}
+
+	for p, typs := range found {
+		if _, ok := types[p]; ok {
+			continue
+		}
+		if slices.Min(typs) == slices.Max(typs) {
+			types[p] = typs[0]
+		}
+	}
+}
+
+// dupeColumn returns an error if a column name is used more than once.
+func dupeColumn(cols []queryCol) error {
+	seen := make(map[string]struct{})
+	for _, c := range cols {
+		if _, ok := seen[c.name]; ok {
+			return fmt.Errorf("duplicate column %q", c.name)
+		}
+		seen[c.name] = struct{}{}
+	}
+	return nil
+}
+
+// queryCol is a result column of a query.
+type queryCol struct {
+	name     string
+	typ      string // Database type, in lower case.
+	nullable bool
+}
+
+// describeQuery gets the result columns for the query, or nil if it's not a
+// query that returns rows.
+//
+// Queries are never run: a select is wrapped in a subquery with "limit 0" to
+// get the columns, and the type of the columns in a returning clause are taken
+// from the table.
+func describeQuery(ctx context.Context, name, query string, isTpl bool, params []string) ([]queryCol, error) {
+	if isTpl {
+		query = reTplAction.ReplaceAllString(query, "")
+	}
+	var (
+		kind   string
+		config = tokenConfig(SQLDialect(ctx))
+	)
+	for _, t := range sqltoken.Tokenize(query, config) {
+		if t.Type != sqltoken.Word {
+			continue
+		}
+		w := strings.ToLower(t.Text)
+		if kind == "" {
+			kind = w
+		}
+		if w == "returning" {
+			kind = w
+			break
+		}
+	}
+	if kind != "select" && kind != "with" && kind != "values" && kind != "returning" {
+		return nil, nil
+	}
+
+	p := make(map[string]any, len(params))
+	for _, n := range params {
+		p[n] = nil
+	}
+	db := MustGetDB(ctx)
+	query, args, err := prepareImpl(ctx, db, "load:"+name, p)
+	if err != nil {
+		return nil, err
+	}
+	query = strings.TrimRight(strings.TrimSpace(query), ";")
+
+	if kind == "returning" {
+		return returningColumns(ctx, sqltoken.Tokenize(query, config))
+	}
+
+	var cols []queryCol
+	err = TX(ctx, func(ctx context.Context) error {
+		// Newline, as the query may end with a -- comment.
+		r, err := MustGetDB(ctx).(dbImpl).QueryxContext(ctx,
+			"select * from (\n"+query+"\n) zdb_describe limit 0", args...)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		ct, err := r.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		for _, c := range ct {
+			null, ok := c.Nullable()
+			cols = append(cols, queryCol{name: c.Name(), typ: strings.ToLower(c.DatabaseTypeName()), nullable: ok && null})
+		}
+		return TXRollback
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cols, nil
+}
+
+// returningColumns gets the columns from the returning clause of an insert,
+// update, or delete. Only columns (and *) are supported, not expressions.
+func returningColumns(ctx context.Context, tokens sqltoken.Tokens) ([]queryCol, error) {
+	var (
+		words = make(sqltoken.Tokens, 0, len(tokens))
+		table string
+		ret   = -1
+	)
+	for i, t := range tokens {
+		if t.Type != sqltoken.Whitespace && t.Type != sqltoken.Comment {
+			words = append(words, t)
+		}
+		if t.Type == sqltoken.Word && strings.EqualFold(t.Text, "returning") {
+			ret = i + 1
+		}
+	}
+	for i, t := range words {
+		w := strings.ToLower(t.Text)
+		if t.Type != sqltoken.Word || i+1 >= len(words) {
+			continue
+		}
+		if table == "" && (w == "into" || w == "update" || (w == "from" && strings.EqualFold(words[0].Text, "delete"))) {
+			j := i + 1
+			if strings.EqualFold(words[j].Text, "only") && j+1 < len(words) {
+				j++
+			}
+			table = unquoteIdent(words[j].Text)
+			if j+2 < len(words) && words[j+1].Text == "." {
+				table = unquoteIdent(words[j+2].Text)
+			}
+		}
+	}
+	if table == "" || ret == -1 {
+		return nil, errors.New("can't find table name or returning clause")
+	}
+
+	tblCols, err := Columns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(tblCols) == 0 {
+		return nil, fmt.Errorf("no such table: %q", table)
+	}
+	find := func(name string) (Column, bool) {
+		i := slices.IndexFunc(tblCols, func(c Column) bool { return strings.EqualFold(c.Name, name) })
+		if i == -1 {
+			return Column{}, false
+		}
+		return tblCols[i], true
+	}
+
+	// Split on commas outside of parentheses.
+	var (
+		items [][]sqltoken.Token
+		cur   []sqltoken.Token
+		depth int
+	)
+	for _, t := range tokens[ret:] {
+		if t.Type == sqltoken.Comment {
+			continue
+		}
+		if t.Type != sqltoken.Punctuation {
+			cur = append(cur, t)
+			continue
+		}
+		for _, c := range t.Text {
+			switch {
+			case c == ',' && depth == 0:
+				items, cur = append(items, cur), nil
+				continue
+			case c == '(':
+				depth++
+			case c == ')':
+				depth--
+			}
+			cur = append(cur, sqltoken.Token{Type: sqltoken.Punctuation, Text: string(c)})
+		}
+	}
+	items = append(items, cur)
+
+	var cols []queryCol
+	for _, raw := range items {
+		item := make([]sqltoken.Token, 0, len(raw))
+		for _, t := range raw {
+			if t.Type != sqltoken.Whitespace {
+				item = append(item, t)
+			}
+		}
+		if len(item) == 1 && item[0].Text == "*" {
+			for _, c := range tblCols {
+				cols = append(cols, queryCol{name: c.Name, typ: strings.ToLower(c.Type), nullable: c.Nullable})
+			}
+			continue
+		}
+
+		var alias string
+		if n := len(item); n >= 3 && strings.EqualFold(item[n-2].Text, "as") {
+			alias, item = unquoteIdent(item[n-1].Text), item[:n-2]
+		} else if n >= 2 && isIdent(item[n-1]) && isIdent(item[n-2]) {
+			alias, item = unquoteIdent(item[n-1].Text), item[:n-1]
+		}
+		if n := len(item); n == 3 && item[1].Text == "." && isIdent(item[0]) {
+			item = item[2:]
+		}
+		if len(item) != 1 || !isIdent(item[0]) {
+			return nil, fmt.Errorf("can't determine type of %q in returning clause; only columns are supported",
+				strings.TrimSpace(sqltoken.Tokens(raw).String()))
+		}
+
+		c, ok := find(unquoteIdent(item[0].Text))
+		if !ok {
+			return nil, fmt.Errorf("no column %q in table %q", unquoteIdent(item[0].Text), table)
+		}
+		if alias == "" {
+			alias = c.Name
+		}
+		cols = append(cols, queryCol{name: alias, typ: strings.ToLower(c.Type), nullable: c.Nullable})
+	}
+	return cols, nil
+}
+
+func isIdent(t sqltoken.Token) bool {
+	return t.Type == sqltoken.Word || (t.Type == sqltoken.Literal && strings.HasPrefix(t.Text, `"`)) ||
+		(len(t.Text) > 1 && t.Text[0] == '`')
+}
+
+// unquoteIdent removes the quotes from a quoted identifier.
+func unquoteIdent(s string) string {
+	if len(s) > 1 && (s[0] == '"' || s[0] == '`') && s[len(s)-1] == s[0] {
+		q := s[:1]
+		return strings.ReplaceAll(s[1:len(s)-1], q+q, q)
+	}
+	return s
+}
+
+// goType gets the Go type for the database type typ, as reported by Columns()
+// or the driver.
+//
+// numeric and decimal are a string, as float64 would lose precision; use
+// GenerateOptions.Types to map them to a decimal type.
+func goType(dialect Dialect, typ string) string {
+	typ = strings.ToLower(typ)
+	base, _, _ := strings.Cut(typ, "(")
+	base = strings.TrimSpace(base)
+	base = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(base, "unsigned "), " unsigned"))
+
+	switch {
+	// MariaDB uses tinyint(1) for "boolean".
+	case base == "bool" || base == "boolean" || (dialect == DialectMariaDB && typ == "tinyint(1)"):
+		return "bool"
+	case base == "date" || base == "datetime" || strings.HasPrefix(base, "timestamp"):
+		return "time.Time"
+	case slices.Contains(intTypes, base):
+		return "int64"
+	case slices.Contains(floatTypes, base) || strings.HasPrefix(base, "double"):
+		return "float64"
+	case base == "bytea" || strings.HasSuffix(base, "blob") || strings.HasSuffix(base, "binary"):
+		return "[]byte"
+	default:
+		return "string"
+	}
+}
+
+var (
+	intTypes = []string{"integer", "int", "tinyint", "smallint", "mediumint", "bigint",
+		"int2", "int4", "int8", "big int", "serial", "smallserial", "bigserial",
+		"serial2", "serial4", "serial8"}
+	floatTypes = []string{"real", "float", "float4", "float8"}
+)
+
+// Common initialisms; this is the same list as golint uses.
+var initialisms = []string{"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF",
+	"GUID", "HTML", "HTTP", "HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM",
+	"RHS", "RPC", "SLA", "SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI",
+	"UID", "UUID", "URI", "URL", "UTF8", "VM", "XML", "XMPP", "XSRF", "XSS"}
+
+// goName converts an SQL name such as "site_id" to an exported Go name such as
+// "SiteID".
+func goName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, w := range words {
+		if u := strings.ToUpper(w); slices.Contains(initialisms, u) {
+			b.WriteString(u)
+			continue
+		}
+		r := []rune(w)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+	n := b.String()
+	if n == "" || !unicode.IsLetter([]rune(n)[0]) {
+		n = "X" + n
+	}
+	return n
+}
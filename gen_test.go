@@ -0,0 +1,67 @@
+package zdb
+
+import "testing"
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"sites", "Sites"},
+		{"site_id", "SiteID"},
+		{"user_agent_url", "UserAgentURL"},
+		{"Created-At", "CreatedAt"},
+		{"2fa", "X2fa"},
+		{"uuid", "UUID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			have := goName(tt.in)
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoType(t *testing.T) {
+	tests := []struct {
+		dialect  Dialect
+		in, want string
+	}{
+		{DialectSQLite, "integer", "int64"},
+		{DialectSQLite, "varchar(20)", "string"},
+		{DialectSQLite, "timestamp", "time.Time"},
+		{DialectSQLite, "blob", "[]byte"},
+		{DialectSQLite, "", "string"},
+		{DialectPostgreSQL, "bigint", "int64"},
+		{DialectPostgreSQL, "timestamp with time zone", "time.Time"},
+		{DialectPostgreSQL, "time without time zone", "string"},
+		{DialectPostgreSQL, "interval", "string"},
+		{DialectPostgreSQL, "double precision", "float64"},
+		{DialectPostgreSQL, "boolean", "bool"},
+		{DialectPostgreSQL, "bytea", "[]byte"},
+		{DialectPostgreSQL, "character varying(20)", "string"},
+		{DialectMariaDB, "tinyint(1)", "bool"},
+		{DialectMariaDB, "tinyint(4)", "int64"},
+		{DialectMariaDB, "int(10) unsigned", "int64"},
+		{DialectMariaDB, "datetime", "time.Time"},
+		{DialectMariaDB, "longblob", "[]byte"},
+		{DialectMariaDB, "UNSIGNED BIGINT", "int64"},
+		{DialectMariaDB, "decimal(10,2)", "string"},
+		{DialectPostgreSQL, "numeric", "string"},
+		{DialectPostgreSQL, "INT8", "int64"},
+		{DialectPostgreSQL, "FLOAT8", "float64"},
+		{DialectPostgreSQL, "_int8", "string"},
+		{DialectPostgreSQL, "point", "string"},
+		{DialectPostgreSQL, "timetz", "string"},
+		{DialectSQLite, "unsigned big int", "int64"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			have := goType(tt.dialect, tt.in)
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+		})
+	}
+}
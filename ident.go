@@ -0,0 +1,155 @@
+package zdb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrOrderBy is returned if the input to [OrderBy] contains a column that's not
+// allowed.
+//
+// This is typically user input, so you may want to return a "400 Bad Request"
+// or similar if you get this error.
+var ErrOrderBy = errors.New("zdb.OrderBy: invalid column")
+
+// Ident is an SQL identifier, such as a table or column name; when used as a
+// parameter it's inserted directly in the query, quoted with [QuoteIdentifier]:
+//
+//	zdb.Select(ctx, &rows, `select ? from t`, zdb.Ident("name"))
+//
+// This can be used as both a named and positional parameter.
+type Ident string
+
+// Order is an "order by" clause; see [OrderBy].
+type Order struct {
+	input   string
+	allowed map[string]string
+}
+
+// OrderBy creates a parameter for an "order by" clause from user input, such as
+// a query parameter in an HTTP request:
+//
+//	order := zdb.OrderBy(r.URL.Query().Get("sort"), map[string]string{
+//		"name":    "",
+//		"created": "users.created_at",
+//	})
+//	err := zdb.Select(ctx, &rows, `select * from users order by ?`, order)
+//
+// The input is a comma-separated list of columns; prefix a column with "-" or
+// add " desc" to sort descending, e.g. "name,-created" or "created desc".
+//
+// Every column must be a key in allowed; the value is the SQL expression that's
+// inserted in the query, or the quoted key if it's "". Anything not in allowed
+// is rejected with [ErrOrderBy] when the query is prepared.
+func OrderBy(input string, allowed map[string]string) Order {
+	return Order{input: input, allowed: allowed}
+}
+
+func (o Order) sql(q byte) (SQL, error) {
+	if strings.TrimSpace(o.input) == "" {
+		return "", fmt.Errorf("%w: no columns", ErrOrderBy)
+	}
+
+	var b strings.Builder
+	for i, c := range strings.Split(o.input, ",") {
+		c = strings.TrimSpace(c)
+		dir := "asc"
+		switch {
+		case strings.HasPrefix(c, "-"):
+			c, dir = c[1:], "desc"
+		case strings.HasPrefix(c, "+"):
+			c = c[1:]
+		default:
+			if f := strings.Fields(c); len(f) == 2 {
+				switch strings.ToLower(f[1]) {
+				case "asc", "desc":
+					c, dir = f[0], strings.ToLower(f[1])
+				}
+			}
+		}
+
+		expr, ok := o.allowed[c]
+		if !ok {
+			return "", fmt.Errorf("%w: %q", ErrOrderBy, c)
+		}
+		if expr == "" {
+			expr = quoteIdent(q, c)
+		}
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(expr)
+		b.WriteByte(' ')
+		b.WriteString(dir)
+	}
+	return SQL(b.String()), nil
+}
+
+// quoteIdent quotes ident as an SQL identifier with the quote character q.
+func quoteIdent(q byte, ident string) string {
+	var b strings.Builder
+	b.Grow(len(ident) + 2)
+	b.WriteByte(q)
+	for i := 0; i < len(ident); i++ {
+		if ident[i] == q {
+			b.WriteByte(q)
+		}
+		b.WriteByte(ident[i])
+	}
+	b.WriteByte(q)
+	return b.String()
+}
+
+// identQuote gets the character to quote identifiers with.
+func identQuote(db DB) byte {
+	if q, ok := Unwrap(db).(interface{ identQuote() byte }); ok {
+		return q.identQuote()
+	}
+	if db.SQLDialect() == DialectMariaDB {
+		return '`'
+	}
+	return '"'
+}
+
+// expandIdents converts Ident and Order parameters to SQL, quoting identifiers
+// with q; params is the result of prepareParams().
+func expandIdents(q byte, params any) (any, error) {
+	conv := func(p any) (any, bool, error) {
+		switch pp := p.(type) {
+		case Ident:
+			if pp == "" || strings.ContainsRune(string(pp), 0) {
+				return nil, false, fmt.Errorf("invalid identifier %q", pp)
+			}
+			return SQL(quoteIdent(q, string(pp))), true, nil
+		case Order:
+			s, err := pp.sql(q)
+			return s, true, err
+		}
+		return p, false, nil
+	}
+
+	switch pp := params.(type) {
+	case map[string]any:
+		for k, v := range pp {
+			s, ok, err := conv(v)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %q: %w", k, err)
+			}
+			if ok {
+				pp[k] = s
+			}
+		}
+	case []any:
+		for i, v := range pp {
+			s, ok, err := conv(v)
+			if err != nil {
+				return nil, fmt.Errorf("parameter %d: %w", i+1, err)
+			}
+			if ok {
+				pp[i] = s
+			}
+		}
+	}
+	return params, nil
+}
@@ -0,0 +1,101 @@
+package zdb
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"zgo.at/zstd/ztest"
+)
+
+func TestIdent(t *testing.T) {
+	allowed := map[string]string{"name": "", "date": "t.created_at", "x\"y": ""}
+
+	tests := []struct {
+		query   string
+		params  []any
+		want    string
+		wantErr string
+	}{
+		{`select ? from t`, []any{Ident("name")},
+			`select "name" from t []interface {}{}`, ""},
+		{`select ? from t where x = ?`, []any{Ident(`a"b`), 1},
+			`select "a""b" from t where x = ? []interface {}{1}`, ""},
+		{`select :col from t where x = :x`, []any{map[string]any{"col": Ident("name"), "x": 1}},
+			`select "name" from t where x = ? []interface {}{1}`, ""},
+		{`select ? from t`, []any{Ident("")},
+			``, "invalid identifier"},
+
+		{`select * from t order by ?`, []any{OrderBy("name", allowed)},
+			`select * from t order by "name" asc []interface {}{}`, ""},
+		{`select * from t order by ?`, []any{OrderBy("-name, date", allowed)},
+			`select * from t order by "name" desc, t.created_at asc []interface {}{}`, ""},
+		{`select * from t order by ?`, []any{OrderBy("date DESC,+name", allowed)},
+			`select * from t order by t.created_at desc, "name" asc []interface {}{}`, ""},
+		{`select * from t order by ?`, []any{OrderBy(`x"y`, allowed)},
+			`select * from t order by "x""y" asc []interface {}{}`, ""},
+		{`select * from t where x = :x order by :order`, []any{map[string]any{"x": 1, "order": OrderBy("-date", allowed)}},
+			`select * from t where x = ? order by t.created_at desc []interface {}{1}`, ""},
+
+		{`select * from t order by ?`, []any{OrderBy("", allowed)},
+			``, "no columns"},
+		{`select * from t order by ?`, []any{OrderBy("name; drop table t", allowed)},
+			``, `invalid column: "name; drop table t"`},
+		{`select * from t order by ?`, []any{OrderBy("name desc nulls first", allowed)},
+			``, `invalid column`},
+		{`select * from t order by ?`, []any{OrderBy("Name", allowed)},
+			``, `invalid column: "Name"`},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			ctx := testdriver(t)
+
+			query, params, err := prepareImpl(ctx, MustGetDB(ctx), tt.query, tt.params...)
+			if !ztest.ErrorContains(err, tt.wantErr) {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				if tt.wantErr != "invalid identifier" && !errors.Is(err, ErrOrderBy) {
+					t.Errorf("not ErrOrderBy: %v", err)
+				}
+				return
+			}
+
+			have := fmt.Sprintf("%s %#v", query, params)
+			if have != tt.want {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := []struct {
+		q    byte
+		in   string
+		want string
+	}{
+		{'"', `col`, `"col"`},
+		{'"', `a"b`, `"a""b"`},
+		{'"', "a`b", "\"a`b\""},
+		{'`', `col`, "`col`"},
+		{'`', "a`b", "`a``b`"},
+		{'`', `a"b`, "`a\"b`"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have := quoteIdent(tt.q, tt.in)
+			if have != tt.want {
+				t.Errorf("\nhave: %s\nwant: %s", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	if have, want := QuoteIdentifier(`a"b`), `"a""b"`; have != want {
+		t.Errorf("\nhave: %s\nwant: %s", have, want)
+	}
+}
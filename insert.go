@@ -2,13 +2,16 @@ package zdb
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
 	"slices"
 	"strings"
+	"time"
 
 	"zgo.at/zstd/zreflect"
+	"zgo.at/zstd/ztime"
 )
 
 type (
@@ -26,12 +29,16 @@ type (
 	}
 )
 
-func idcol(opts [][]string) (int, error) {
+func idcol(opts [][]string) (int, error) { return optcol(opts, "id") }
+
+// optcol gets the index of the column with the option opt, or -1 if there is
+// none. It's an error if more than one column has the option.
+func optcol(opts [][]string, opt string) (int, error) {
 	col := -1
 	for i, o := range opts {
-		if slices.Contains(o, "id") {
+		if slices.Contains(o, opt) {
 			if col != -1 {
-				return -1, errors.New("more than one field with ,id option")
+				return -1, fmt.Errorf("more than one field with ,%s option", opt)
 			}
 			col = i
 		}
@@ -39,14 +46,78 @@ func idcol(opts [][]string) (int, error) {
 	return col, nil
 }
 
+// columnFields gets the column names, values, and options for all fields in
+// t, like zreflect.Fields() with the db tag.
+//
+// Fields with the ",prefix" option are skipped: these are loaded from joined
+// tables and aren't columns in the table.
+func columnFields(t any, skip string) ([]string, []any, [][]string) {
+	cols, vals, opts := zreflect.Fields(t, "db", skip)
+	n := 0
+	for i := range cols {
+		if slices.ContainsFunc(opts[i], func(o string) bool { return strings.HasPrefix(o, "prefix=") }) {
+			continue
+		}
+		cols[n], vals[n], opts[n] = cols[i], vals[i], opts[i]
+		n++
+	}
+	return cols[:n], vals[:n], opts[:n]
+}
+
+// fieldByColumn gets the struct field for the column name col, using the same
+// rules as zreflect.Fields() to get the name.
+//
+// The returned value is invalid if there is no such column.
+func fieldByColumn(t any, col string) reflect.Value {
+	v := reflect.ValueOf(t)
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Type().Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		if f.Type.Kind() == reflect.Struct && f.Anonymous {
+			if ef := fieldByColumn(v.Field(i).Addr().Interface(), col); ef.IsValid() {
+				return ef
+			}
+			continue
+		}
+
+		name, _ := zreflect.Tag(f, "db")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if name == col {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
+
 // Insert all struct fields of t.
 //
 // Column names are taken from the db tag. Fields with the db tag set to "-" or
-// with the ",noinsert" option will be skipped.
+// with the ",noinsert" option will be skipped, as are fields with the ",prefix"
+// option for nested structs or slices (see [Select]); this applies to all
+// functions that write or load rows from a struct.
 //
 // If a field has the ",id" option it will be fetched with a "returning" clause
 // and set.
 //
+// Fields with the ",json" option are marshalled with encoding/json and stored
+// as text; a nil pointer is stored as NULL. The same applies to [Update] and to
+// named parameters from a struct.
+//
+// Fields with the ",created" or ",updated" option are set to the current time
+// if they're the zero value. The time is taken from ztime.Now(), so it can be
+// set with ztime.WithNow() on the context. The field must be a time.Time or
+// *time.Time.
+//
 // The Default() and Validator() methods will be called if t satisfies the
 // [Defaulter] or [Validator] interface.
 //
@@ -62,6 +133,10 @@ func Insert(ctx context.Context, t Tabler, onConflict ...string) error {
 	if d, ok := t.(Defaulter); ok {
 		d.Defaults(ctx)
 	}
+	err := setTimestamps(ctx, t, true)
+	if err != nil {
+		return fmt.Errorf("zdb.Insert: %w", err)
+	}
 	if v, ok := t.(Validator); ok {
 		err := v.Validate(ctx)
 		if err != nil {
@@ -69,7 +144,11 @@ func Insert(ctx context.Context, t Tabler, onConflict ...string) error {
 		}
 	}
 
-	cols, params, opts := zreflect.Fields(t, "db", "noinsert")
+	cols, params, opts := columnFields(t, "noinsert")
+	err = jsonParams(cols, params, opts)
+	if err != nil {
+		return fmt.Errorf("zdb.Insert: %w", err)
+	}
 
 	// Get the ID column, if any
 	idCol, err := idcol(opts)
@@ -81,7 +160,7 @@ func Insert(ctx context.Context, t Tabler, onConflict ...string) error {
 		idColName string
 	)
 	if idCol > -1 {
-		id := reflect.ValueOf(t).Elem().Field(idCol)
+		id := fieldByColumn(t, cols[idCol])
 		if !id.IsZero() {
 			return fmt.Errorf(`zdb.Insert: id field %q is not zero value but "%v"`, cols[idCol], id.Interface())
 		}
@@ -91,16 +170,16 @@ func Insert(ctx context.Context, t Tabler, onConflict ...string) error {
 	}
 
 	for i := range cols {
-		cols[i] = QuoteIdentifier(cols[i])
+		cols[i] = QuoteIdentifierContext(ctx, cols[i])
 	}
 	q := fmt.Sprintf(`insert into %s (%s) values (?) %s`,
-		QuoteIdentifier(t.Table()),
+		QuoteIdentifierContext(ctx, t.Table()),
 		strings.Join(cols, ", "),
 		strings.Join(onConflict, " "))
 	if idColName == "" {
 		err = Exec(ctx, q, params)
 	} else {
-		q += " returning " + QuoteIdentifier(idColName)
+		q += " returning " + QuoteIdentifierContext(ctx, idColName)
 		err = Get(ctx, idVal, q, params)
 	}
 	if err != nil {
@@ -109,21 +188,169 @@ func Insert(ctx context.Context, t Tabler, onConflict ...string) error {
 	return nil
 }
 
-// QuoteIdentifier quotes ident as an SQL identifier.
-func QuoteIdentifier(ident string) string {
-	var b strings.Builder
-	b.Grow(len(ident) + 2)
-	b.WriteByte('"')
-	for _, c := range ident {
-		if c == '"' {
-			b.WriteByte('"')
+// InsertAll inserts all rows.
+//
+// This works like [Insert], except that as many rows as possible are inserted
+// with a single query. If the rows don't fit in a single query then multiple
+// queries are run in a transaction.
+//
+// If a field has the ",id" option then it will be set for every row. None of
+// the databases guarantee the order of rows returned from a multi-row insert,
+// so the returned IDs are sorted and assigned in the order of rows. This relies
+// on auto-increment columns handing out IDs in increasing order within a
+// single statement, which is the case for SQLite, PostgreSQL (serial and
+// identity columns), and MariaDB. IDs that aren't integers, such as UUIDs,
+// can't be matched this way, and those rows are inserted with a separate query
+// per row (in a transaction).
+func InsertAll[T Tabler](ctx context.Context, rows []T) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if reflect.TypeOf(rows[0]).Kind() != reflect.Ptr {
+		return errors.New("zdb.InsertAll: T is not a pointer")
+	}
+
+	var (
+		cols      []string
+		params    = make([][]any, 0, len(rows))
+		ids       = make([]reflect.Value, 0, len(rows))
+		idColName string
+		intID     bool
+	)
+	for i, t := range rows {
+		if d, ok := any(t).(Defaulter); ok {
+			d.Defaults(ctx)
+		}
+		err := setTimestamps(ctx, t, true)
+		if err != nil {
+			return fmt.Errorf("zdb.InsertAll: row %d: %w", i, err)
+		}
+		if v, ok := any(t).(Validator); ok {
+			err := v.Validate(ctx)
+			if err != nil {
+				return fmt.Errorf("zdb.InsertAll: row %d: %w", i, err)
+			}
+		}
+
+		c, p, opts := columnFields(t, "noinsert")
+		err = jsonParams(c, p, opts)
+		if err != nil {
+			return fmt.Errorf("zdb.InsertAll: row %d: %w", i, err)
+		}
+		idCol, err := idcol(opts)
+		if err != nil {
+			return fmt.Errorf("zdb.InsertAll: %w", err)
+		}
+		if idCol > -1 {
+			id := fieldByColumn(t, c[idCol])
+			if !id.IsZero() {
+				return fmt.Errorf(`zdb.InsertAll: row %d: id field %q is not zero value but "%v"`, i, c[idCol], id.Interface())
+			}
+			ids, idColName = append(ids, id), c[idCol]
+			intID = id.CanInt() || id.CanUint()
+			p, c = append(p[:idCol], p[idCol+1:]...), append(c[:idCol], c[idCol+1:]...)
+		}
+		if cols == nil {
+			cols = c
+		}
+		params = append(params, p)
+	}
+
+	for i := range cols {
+		cols[i] = QuoteIdentifierContext(ctx, cols[i])
+	}
+
+	// Neither PostgreSQL, SQLite, nor MariaDB guarantee that "returning" gives
+	// the rows in the same order as the values, so there's no way to know which
+	// ID belongs to which row unless it's an auto-increment integer. Insert them
+	// one by one if we can't match the IDs.
+	if idColName != "" && !intID {
+		err := TX(ctx, func(ctx context.Context) error {
+			for i, p := range params {
+				b := newBuilder(rows[0].Table(), cols...)
+				b.values(p...)
+				b.returning = []string{QuoteIdentifierContext(ctx, idColName)}
+				q, p := b.SQL(identQuote(MustGetDB(ctx)))
+				err := Get(ctx, ids[i].Addr().Interface(), q, p...)
+				if err != nil {
+					return fmt.Errorf("row %d: %w", i, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("zdb.InsertAll: %w", err)
+		}
+		return nil
+	}
+
+	// SQLITE_MAX_VARIABLE_NUMBER: https://www.sqlite.org/limits.html
+	limit := 32766 / max(len(cols), 1)
+	err := TX(ctx, func(ctx context.Context) error {
+		for start := 0; start < len(params); start += limit {
+			end := min(start+limit, len(params))
+			b := newBuilder(rows[0].Table(), cols...)
+			for _, p := range params[start:end] {
+				b.values(p...)
+			}
+			if idColName == "" {
+				q, p := b.SQL(identQuote(MustGetDB(ctx)))
+				err := Exec(ctx, q, p...)
+				if err != nil {
+					return err
+				}
+				continue
+			}
+
+			b.returning = []string{QuoteIdentifierContext(ctx, idColName)}
+			q, p := b.SQL(identQuote(MustGetDB(ctx)))
+			var got []int64
+			err := Select(ctx, &got, q, p...)
+			if err != nil {
+				return err
+			}
+			if len(got) != end-start {
+				return fmt.Errorf("%d rows returned for %d rows inserted", len(got), end-start)
+			}
+			slices.Sort(got)
+			for i, id := range got {
+				if v := ids[start+i]; v.CanInt() {
+					v.SetInt(id)
+				} else {
+					v.SetUint(uint64(id))
+				}
+			}
 		}
-		b.WriteRune(c)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("zdb.InsertAll: %w", err)
 	}
-	b.WriteByte('"')
-	return b.String()
+	return nil
+}
+
+// QuoteIdentifier quotes ident as an SQL identifier.
+//
+// This always uses double quotes, which doesn't work on MariaDB unless
+// ANSI_QUOTES is set in sql_mode; use [QuoteIdentifierContext] to quote for
+// the connection.
+func QuoteIdentifier(ident string) string {
+	return quoteIdent('"', ident)
+}
+
+// QuoteIdentifierContext quotes ident as an SQL identifier for the connection.
+//
+// This uses double quotes, except on MariaDB where it uses backticks, since
+// double quotes are string literals there unless ANSI_QUOTES is set in
+// sql_mode (in which case double quotes are used as well).
+func QuoteIdentifierContext(ctx context.Context, ident string) string {
+	return quoteIdent(identQuote(MustGetDB(ctx)), ident)
 }
 
+// ErrConflict is returned by [Update] if the ,version column doesn't match the
+// value in the database.
+var ErrConflict = errors.New("row was modified concurrently")
+
 // UpdateAll signals that all columns should be updated.
 var UpdateAll = "\x00update\x00all\x00"
 
@@ -131,11 +358,19 @@ var UpdateAll = "\x00update\x00all\x00"
 // db tag.
 //
 // All columns in the struct wil be updated if [UpdateAll] is used as a column.
-// Fields with the db tag set to "-" or with the ",noinsert" or ",readonly"
-// option will be skipped.
+// Fields with the db tag set to "-" or with the ",noinsert", ",readonly", or
+// ",created" option will be skipped.
+//
+// Fields with the ",updated" option are always set to the current time and
+// updated, as described in [Insert].
 //
 // t needs to have a db tag with the ,id option set, which is used in the WHERE.
 //
+// If a field has the ",version" option it's used for optimistic locking: the
+// column is added to the WHERE and incremented by one. The field in t is
+// updated on success, and [ErrConflict] is returned if the row was modified
+// since it was loaded (or was deleted).
+//
 // The Default() and Validator() methods will be called if t satisfies the
 // [Defaulter] or [Validator] interface.
 func Update(ctx context.Context, t Tabler, columns ...string) error {
@@ -149,6 +384,10 @@ func Update(ctx context.Context, t Tabler, columns ...string) error {
 	if d, ok := t.(Defaulter); ok {
 		d.Defaults(ctx)
 	}
+	err := setTimestamps(ctx, t, false)
+	if err != nil {
+		return fmt.Errorf("zdb.Update: %w", err)
+	}
 	if v, ok := t.(Validator); ok {
 		err := v.Validate(ctx)
 		if err != nil {
@@ -158,8 +397,12 @@ func Update(ctx context.Context, t Tabler, columns ...string) error {
 
 	var (
 		tbl              = t.Table()
-		cols, vals, opts = zreflect.Fields(t, "db", "")
+		cols, vals, opts = columnFields(t, "")
 	)
+	err = jsonParams(cols, vals, opts)
+	if err != nil {
+		return fmt.Errorf("zdb.Update: %w", err)
+	}
 
 	idCol, err := idcol(opts)
 	if err != nil {
@@ -171,7 +414,19 @@ func Update(ctx context.Context, t Tabler, columns ...string) error {
 	if reflect.ValueOf(vals[idCol]).IsZero() {
 		return errors.New("zdb.Update: ID column is zero value")
 	}
-	where, whereParam := fmt.Sprintf(`%s = ?`, QuoteIdentifier(cols[idCol])), vals[idCol]
+	where, whereParams := fmt.Sprintf(`%s = ?`, QuoteIdentifierContext(ctx, cols[idCol])), []any{vals[idCol]}
+
+	verCol, err := optcol(opts, "version")
+	if err != nil {
+		return fmt.Errorf("zdb.Update: %w", err)
+	}
+	var version reflect.Value
+	if verCol > -1 {
+		version = fieldByColumn(t, cols[verCol])
+		if !version.CanInt() && !version.CanUint() {
+			return fmt.Errorf("zdb.Update: ,version column %q is not an integer but %s", cols[verCol], version.Type())
+		}
+	}
 
 	var (
 		updateAll = len(columns) == 1 && columns[0] == UpdateAll
@@ -179,7 +434,7 @@ func Update(ctx context.Context, t Tabler, columns ...string) error {
 		params    []any
 	)
 	for i := range cols {
-		if i == idCol {
+		if i == idCol || i == verCol {
 			continue
 		}
 		if slices.Contains(opts[i], "noinsert") {
@@ -189,19 +444,366 @@ func Update(ctx context.Context, t Tabler, columns ...string) error {
 			continue
 		}
 		if updateAll {
-			if !slices.Contains(opts[i], "readonly") {
-				set, params = append(set, QuoteIdentifier(cols[i])+` = ?`), append(params, vals[i])
+			if !slices.Contains(opts[i], "readonly") && !slices.Contains(opts[i], "created") {
+				set, params = append(set, QuoteIdentifierContext(ctx, cols[i])+` = ?`), append(params, vals[i])
 			}
-		} else if slices.Contains(columns, cols[i]) {
-			set, params = append(set, QuoteIdentifier(cols[i])+` = ?`), append(params, vals[i])
+		} else if slices.Contains(columns, cols[i]) || slices.Contains(opts[i], "updated") {
+			set, params = append(set, QuoteIdentifierContext(ctx, cols[i])+` = ?`), append(params, vals[i])
 		}
 	}
 
+	if verCol == -1 {
+		q := fmt.Sprintf(`update %s set %s where %s`,
+			QuoteIdentifierContext(ctx, tbl), strings.Join(set, ", "), where)
+		err = Exec(ctx, q, append(params, whereParams...)...)
+		if err != nil {
+			return fmt.Errorf("zdb.Update: %w", err)
+		}
+		return nil
+	}
+
+	set = append(set, fmt.Sprintf(`%[1]s = %[1]s + 1`, QuoteIdentifierContext(ctx, cols[verCol])))
+	where, whereParams = where+fmt.Sprintf(` and %s = ?`, QuoteIdentifierContext(ctx, cols[verCol])), append(whereParams, vals[verCol])
 	q := fmt.Sprintf(`update %s set %s where %s`,
-		QuoteIdentifier(tbl), strings.Join(set, ", "), where)
-	err = Exec(ctx, q, append(params, whereParam)...)
+		QuoteIdentifierContext(ctx, tbl), strings.Join(set, ", "), where)
+	n, err := NumRows(ctx, q, append(params, whereParams...)...)
 	if err != nil {
 		return fmt.Errorf("zdb.Update: %w", err)
 	}
+	if n == 0 {
+		return fmt.Errorf("zdb.Update: %w", ErrConflict)
+	}
+	if version.CanInt() {
+		version.SetInt(version.Int() + 1)
+	} else {
+		version.SetUint(version.Uint() + 1)
+	}
+	return nil
+}
+
+// idColumn gets the name and value of the column with the ,id option.
+func idColumn(t Tabler) (string, any, error) {
+	cols, vals, opts := columnFields(t, "")
+	idCol, err := idcol(opts)
+	if err != nil {
+		return "", nil, err
+	}
+	if idCol == -1 {
+		return "", nil, errors.New("no ,id column")
+	}
+	if reflect.ValueOf(vals[idCol]).IsZero() {
+		return "", nil, errors.New("ID column is zero value")
+	}
+	return cols[idCol], vals[idCol], nil
+}
+
+// FindByID loads the row with the given ID in to t.
+//
+// t needs to have a db tag with the ,id option set, which is used in the WHERE.
+// Only the columns from the db tags are selected, so the table can have columns
+// that are not in t.
+//
+// Returns sql.ErrNoRows if there is no row with this ID.
+func FindByID(ctx context.Context, t Tabler, id any) error {
+	if reflect.TypeOf(t).Kind() != reflect.Ptr {
+		return errors.New("zdb.FindByID: t is not a pointer")
+	}
+	cols, _, opts := columnFields(t, "")
+	idCol, err := idcol(opts)
+	if err != nil {
+		return fmt.Errorf("zdb.FindByID: %w", err)
+	}
+	if idCol == -1 {
+		return errors.New("zdb.FindByID: no ,id column")
+	}
+
+	sel := make([]string, 0, len(cols))
+	for _, c := range cols {
+		sel = append(sel, QuoteIdentifierContext(ctx, c))
+	}
+	err = Get(ctx, t, fmt.Sprintf(`select %s from %s where %s = ?`,
+		strings.Join(sel, ", "), QuoteIdentifierContext(ctx, t.Table()), QuoteIdentifierContext(ctx, cols[idCol])), id)
+	if err != nil {
+		return fmt.Errorf("zdb.FindByID: %w", err)
+	}
+	return nil
+}
+
+// Delete the row t.
+//
+// t needs to have a db tag with the ,id option set, which is used in the WHERE.
+//
+// If a field has the ",deleted" option the row is "soft-deleted" by setting
+// that column to the current time, rather than actually deleting it. The field
+// must be a *time.Time and is set on success. Use [HardDelete] to always
+// delete the row.
+//
+// Returns sql.ErrNoRows if there is no row with this ID, or if the row was
+// already soft-deleted.
+func Delete(ctx context.Context, t Tabler) error {
+	return deleteRow(ctx, t, false)
+}
+
+// HardDelete deletes the row t, ignoring any ",deleted" option.
+//
+// Returns sql.ErrNoRows if there is no row with this ID.
+func HardDelete(ctx context.Context, t Tabler) error {
+	return deleteRow(ctx, t, true)
+}
+
+func deleteRow(ctx context.Context, t Tabler, hard bool) error {
+	fn := "zdb.Delete"
+	if hard {
+		fn = "zdb.HardDelete"
+	}
+
+	col, id, err := idColumn(t)
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	cols, _, opts := columnFields(t, "")
+	delCol, err := optcol(opts, "deleted")
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+
+	var n int64
+	if hard || delCol == -1 {
+		n, err = NumRows(ctx, fmt.Sprintf(`delete from %s where %s = ?`,
+			QuoteIdentifierContext(ctx, t.Table()), QuoteIdentifierContext(ctx, col)), id)
+	} else {
+		if reflect.TypeOf(t).Kind() != reflect.Ptr {
+			return fmt.Errorf("%s: t is not a pointer", fn)
+		}
+		del := fieldByColumn(t, cols[delCol])
+		if _, ok := del.Interface().(*time.Time); !ok {
+			return fmt.Errorf("%s: ,deleted column %q is not a *time.Time but %s", fn, cols[delCol], del.Type())
+		}
+
+		now := ztime.Now(ctx)
+		n, err = NumRows(ctx, fmt.Sprintf(`update %[1]s set %[2]s = ? where %[3]s = ? and %[2]s is null`,
+			QuoteIdentifierContext(ctx, t.Table()), QuoteIdentifierContext(ctx, cols[delCol]), QuoteIdentifierContext(ctx, col)), now, id)
+		if err == nil && n > 0 {
+			del.Set(reflect.ValueOf(&now))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", fn, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", fn, sql.ErrNoRows)
+	}
+	return nil
+}
+
+// setTimestamps sets fields with the ,created and ,updated options to the
+// current time.
+//
+// On insert both are set if they're the zero value; otherwise only ,updated is
+// set, and it's always set.
+func setTimestamps(ctx context.Context, t Tabler, insert bool) error {
+	cols, _, opts := columnFields(t, "")
+	now := ztime.Now(ctx)
+	for i, c := range cols {
+		if !slices.Contains(opts[i], "updated") && !(insert && slices.Contains(opts[i], "created")) {
+			continue
+		}
+
+		f := fieldByColumn(t, c)
+		if insert && !f.IsZero() {
+			continue
+		}
+		if !f.CanSet() {
+			return errors.New("t is not a pointer")
+		}
+		switch f.Interface().(type) {
+		case time.Time:
+			f.Set(reflect.ValueOf(now))
+		case *time.Time:
+			f.Set(reflect.ValueOf(&now))
+		default:
+			return fmt.Errorf("column %q is not a time.Time or *time.Time but %s", c, f.Type())
+		}
+	}
+	return nil
+}
+
+// Exists reports if the row t exists.
+//
+// t needs to have a db tag with the ,id option set, which is used in the WHERE.
+func Exists(ctx context.Context, t Tabler) (bool, error) {
+	col, id, err := idColumn(t)
+	if err != nil {
+		return false, fmt.Errorf("zdb.Exists: %w", err)
+	}
+
+	var exists bool
+	err = Get(ctx, &exists, fmt.Sprintf(`select exists(select 1 from %s where %s = ?)`,
+		QuoteIdentifierContext(ctx, t.Table()), QuoteIdentifierContext(ctx, col)), id)
+	if err != nil {
+		return false, fmt.Errorf("zdb.Exists: %w", err)
+	}
+	return exists, nil
+}
+
+// Upsert inserts t, or updates the existing row if it conflicts with an
+// existing row.
+//
+// conflictColumns are the columns for the ON CONFLICT clause; this must match
+// a primary key or unique constraint. MariaDB always considers all unique
+// constraints and conflictColumns is ignored there.
+//
+// updateColumns are the columns to update on conflict; these are the same as
+// [Update], and [UpdateAll] can be used to update all columns except the
+// conflictColumns.
+//
+// If a field has the ",id" option it's set to the ID of the inserted or updated
+// row. The ID column is included in the insert if it's not the zero value, so
+// it can be used as a conflict column.
+//
+// The Default() and Validator() methods will be called if t satisfies the
+// [Defaulter] or [Validator] interface.
+func Upsert(ctx context.Context, t Tabler, conflictColumns []string, updateColumns ...string) error {
+	if reflect.TypeOf(t).Kind() != reflect.Ptr {
+		return errors.New("zdb.Upsert: t is not a pointer")
+	}
+	if len(conflictColumns) == 0 {
+		return errors.New("zdb.Upsert: no conflict columns")
+	}
+	if len(updateColumns) == 0 {
+		return errors.New("zdb.Upsert: no columns")
+	}
+
+	if d, ok := t.(Defaulter); ok {
+		d.Defaults(ctx)
+	}
+	err := setTimestamps(ctx, t, true)
+	if err != nil {
+		return fmt.Errorf("zdb.Upsert: %w", err)
+	}
+	if v, ok := t.(Validator); ok {
+		err := v.Validate(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	cols, params, opts := columnFields(t, "noinsert")
+	err = jsonParams(cols, params, opts)
+	if err != nil {
+		return fmt.Errorf("zdb.Upsert: %w", err)
+	}
+
+	idCol, err := idcol(opts)
+	if err != nil {
+		return fmt.Errorf("zdb.Upsert: %w", err)
+	}
+	var (
+		id        reflect.Value
+		idColName string
+	)
+	if idCol > -1 {
+		id, idColName = fieldByColumn(t, cols[idCol]), cols[idCol]
+		if id.IsZero() {
+			params, cols, opts = append(params[:idCol], params[idCol+1:]...),
+				append(cols[:idCol], cols[idCol+1:]...),
+				append(opts[:idCol], opts[idCol+1:]...)
+		}
+	}
+
+	var (
+		dialect   = SQLDialect(ctx)
+		updateAll = len(updateColumns) == 1 && updateColumns[0] == UpdateAll
+		set       []string
+	)
+	for _, c := range updateColumns {
+		if c != UpdateAll && !slices.Contains(cols, c) {
+			return fmt.Errorf("zdb.Upsert: unknown column or column with ,noinsert: %q", c)
+		}
+	}
+	for i, c := range cols {
+		if c == idColName || slices.Contains(conflictColumns, c) {
+			continue
+		}
+		if (updateAll && !slices.Contains(opts[i], "readonly") && !slices.Contains(opts[i], "created")) ||
+			slices.Contains(updateColumns, c) || slices.Contains(opts[i], "updated") {
+			if dialect == DialectMariaDB {
+				set = append(set, fmt.Sprintf(`%[1]s = values(%[1]s)`, QuoteIdentifierContext(ctx, c)))
+			} else {
+				set = append(set, fmt.Sprintf(`%[1]s = excluded.%[1]s`, QuoteIdentifierContext(ctx, c)))
+			}
+		}
+	}
+
+	conflict := make([]string, 0, len(conflictColumns))
+	for _, c := range conflictColumns {
+		conflict = append(conflict, QuoteIdentifierContext(ctx, c))
+	}
+	if len(set) == 0 {
+		// Always update something, as "do nothing" won't return the ID of the
+		// existing row.
+		if dialect == DialectMariaDB {
+			set = append(set, fmt.Sprintf(`%[1]s = %[1]s`, conflict[0]))
+		} else {
+			set = append(set, fmt.Sprintf(`%[1]s = excluded.%[1]s`, conflict[0]))
+		}
+	}
+
+	for i := range cols {
+		cols[i] = QuoteIdentifierContext(ctx, cols[i])
+	}
+	q := fmt.Sprintf(`insert into %s (%s) values (?) `, QuoteIdentifierContext(ctx, t.Table()), strings.Join(cols, ", "))
+	switch {
+	case idColName == "":
+		if dialect == DialectMariaDB {
+			q += `on duplicate key update ` + strings.Join(set, ", ")
+		} else {
+			q += fmt.Sprintf(`on conflict (%s) do update set %s`, strings.Join(conflict, ", "), strings.Join(set, ", "))
+		}
+		err = Exec(ctx, q, params)
+
+	// MariaDB doesn't return anything useful with "returning" if the row
+	// already exists; use the last_insert_id(expr) trick instead, which will
+	// make the driver report the ID as the "last insert ID".
+	case dialect == DialectMariaDB:
+		set = append(set, fmt.Sprintf(`%[1]s = last_insert_id(%[1]s)`, QuoteIdentifierContext(ctx, idColName)))
+		q += `on duplicate key update ` + strings.Join(set, ", ")
+		err = upsertLastInsertID(ctx, id, q, params)
+
+	default:
+		q += fmt.Sprintf(`on conflict (%s) do update set %s returning %s`,
+			strings.Join(conflict, ", "), strings.Join(set, ", "), QuoteIdentifierContext(ctx, idColName))
+		err = Get(ctx, id.Addr().Interface(), q, params)
+	}
+	if err != nil {
+		return fmt.Errorf("zdb.Upsert: %w\n%s", err, q)
+	}
+	return nil
+}
+
+func upsertLastInsertID(ctx context.Context, id reflect.Value, query string, params ...any) error {
+	db := MustGetDB(ctx)
+	query, params, err := prepareImpl(ctx, db, query, params...)
+	if err != nil {
+		return err
+	}
+	r, err := db.(dbImpl).ExecContext(ctx, query, params...)
+	if err != nil {
+		return err
+	}
+	n, err := r.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	switch id.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		id.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		id.SetUint(uint64(n))
+	default:
+		return fmt.Errorf("id column must be an integer for MariaDB; is %s", id.Type())
+	}
 	return nil
 }
@@ -92,6 +92,12 @@ type Config struct {
 
 	// NoticeAtIdentifiers _baz @fo$o @@b#ar #foo ##b@ar(SQL Server)
 	NoticeIdentifiers bool
+
+	// NoticeBacktickQuotes `foo` `a``b` as type Literal (SQLite)
+	NoticeBacktickQuotes bool
+
+	// NoBackslashEscapes 'a\' "a\" backslashes don't escape quotes (SQLite)
+	NoBackslashEscapes bool
 }
 
 type Tokens []Token
@@ -144,6 +150,16 @@ func PostgreSQLConfig() Config {
 	}
 }
 
+// SQLiteConfig returns a parsing configuration that is appropriate
+// for parsing SQLite SQL.
+func SQLiteConfig() Config {
+	return Config{
+		NoticeQuestionMark:   true,
+		NoticeBacktickQuotes: true,
+		NoBackslashEscapes:   true,
+	}
+}
+
 // TokenizeMySQL breaks up MySQL / MariaDB / SingleStore SQL strings into
 // Token objects.
 func TokenizeMySQL(s string) Tokens {
@@ -248,7 +264,12 @@ BaseState:
 				goto ColonWordStart
 			}
 			token(Punctuation)
-		case '~', '`', '!', '%', '^', '&', '*', '(', ')', '+', '=', '{', '}', '[', ']',
+		case '`':
+			if config.NoticeBacktickQuotes {
+				goto BacktickQuote
+			}
+			token(Punctuation)
+		case '~', '!', '%', '^', '&', '*', '(', ')', '+', '=', '{', '}', '[', ']',
 			'|', '\\', '<', '>', ',':
 			token(Punctuation)
 		case '$':
@@ -373,6 +394,9 @@ SingleQuoteString:
 			token(Literal)
 			goto BaseState
 		case '\\':
+			if config.NoBackslashEscapes {
+				continue
+			}
 			if i < len(s) {
 				i++
 			} else {
@@ -393,6 +417,9 @@ DoubleQuoteString:
 			token(Literal)
 			goto BaseState
 		case '\\':
+			if config.NoBackslashEscapes {
+				continue
+			}
 			if i < len(s) {
 				i++
 			} else {
@@ -404,6 +431,18 @@ DoubleQuoteString:
 	token(Literal)
 	goto Done
 
+BacktickQuote:
+	for i < len(s) {
+		c := s[i]
+		i++
+		if c == '`' {
+			token(Literal)
+			goto BaseState
+		}
+	}
+	token(Literal)
+	goto Done
+
 SkipToEOL:
 	for i < len(s) {
 		c := s[i]
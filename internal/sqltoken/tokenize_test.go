@@ -1159,6 +1159,32 @@ var oddball2Cases = []Tokens{
 	},
 }
 
+var sqliteCases = []Tokens{
+	{
+		{Type: Word, Text: "s01"},
+		{Type: Whitespace, Text: " "},
+		{Type: Literal, Text: "`a;b`"},
+		{Type: Punctuation, Text: ","},
+		{Type: Literal, Text: "`a``b`"},
+	},
+	{
+		{Type: Word, Text: "s02"},
+		{Type: Whitespace, Text: " "},
+		{Type: Literal, Text: `'a\'`},
+		{Type: Semicolon, Text: ";"},
+		{Type: Literal, Text: `"b\"`},
+		{Type: Semicolon, Text: ";"},
+		{Type: Literal, Text: `'c''d'`},
+	},
+	{
+		{Type: Word, Text: "s03"},
+		{Type: Whitespace, Text: " "},
+		{Type: QuestionMark, Text: "?"},
+		{Type: Whitespace, Text: " "},
+		{Type: Literal, Text: "`unterminated ;"},
+	},
+}
+
 func doTests(t *testing.T, config Config, cases ...[]Tokens) {
 	for _, tcl := range cases {
 		for _, tc := range tcl {
@@ -1194,6 +1220,10 @@ func TestPostgresSQLTokenizing(t *testing.T) {
 	doTests(t, PostgreSQLConfig(), commonCases, postgreSQLCases)
 }
 
+func TestSQLiteTokenizing(t *testing.T) {
+	doTests(t, SQLiteConfig(), sqliteCases)
+}
+
 func TestOracleTokenizing(t *testing.T) {
 	doTests(t, OracleConfig(), commonCases, oracleCases)
 }
@@ -0,0 +1,183 @@
+package sqlx
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"zgo.at/zdb/internal/sqlx/reflectx"
+)
+
+// Decoders registered with RegisterDecoder(); this is copied on write, so
+// reading it doesn't need a lock.
+//
+// zdb only.
+var (
+	decodersMu sync.Mutex
+	decoders   atomic.Pointer[map[reflect.Type]func(any) (any, error)]
+)
+
+// RegisterDecoder registers a function to convert database values to the type
+// t. This is used when scanning to t or *t, including struct fields.
+//
+// The function is never called for NULL values; the destination is set to the
+// zero value instead.
+//
+// zdb only.
+func RegisterDecoder(t reflect.Type, fn func(src any) (any, error)) {
+	decodersMu.Lock()
+	defer decodersMu.Unlock()
+
+	m := make(map[reflect.Type]func(any) (any, error))
+	if cur := decoders.Load(); cur != nil {
+		for k, v := range *cur {
+			m[k] = v
+		}
+	}
+	m[t] = fn
+	decoders.Store(&m)
+}
+
+// decoder gets the decoder for t, or nil if there is none.
+func decoder(t reflect.Type) func(any) (any, error) {
+	m := decoders.Load()
+	if m == nil {
+		return nil
+	}
+	return (*m)[t]
+}
+
+// ScanDest wraps dest in an sql.Scanner if it's a pointer to a type with a
+// registered decoder, or a pointer to a pointer of such a type. Other values
+// are returned as-is.
+//
+// zdb only.
+func ScanDest(dest any) any {
+	if decoders.Load() == nil {
+		return dest
+	}
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return dest
+	}
+	t := v.Type().Elem()
+	if dec := decoder(t); dec != nil {
+		return &decodeScanner{dest: v.Elem(), dec: dec}
+	}
+	if t.Kind() == reflect.Ptr {
+		if dec := decoder(t.Elem()); dec != nil {
+			return &decodeScanner{dest: v.Elem(), dec: dec}
+		}
+	}
+	return dest
+}
+
+// ScanDests calls ScanDest for all values in dest. The caller's slice is never
+// modified: a copy is returned if any value was wrapped.
+//
+// zdb only.
+func ScanDests(dest []any) []any {
+	if decoders.Load() == nil {
+		return dest
+	}
+	var cp []any
+	for i, d := range dest {
+		if s, ok := ScanDest(d).(*decodeScanner); ok {
+			if cp == nil {
+				cp = slices.Clone(dest)
+			}
+			cp[i] = s
+		}
+	}
+	if cp == nil {
+		return dest
+	}
+	return cp
+}
+
+type decodeScanner struct {
+	dest reflect.Value
+	dec  func(any) (any, error)
+}
+
+func (s *decodeScanner) Scan(src any) error {
+	if src == nil {
+		s.dest.SetZero()
+		return nil
+	}
+	val, err := s.dec(src)
+	if err != nil {
+		return err
+	}
+
+	rv := reflect.ValueOf(val)
+	t := s.dest.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if !rv.IsValid() || rv.Type() != t {
+		return fmt.Errorf("decoder for %s returned %T", t, val)
+	}
+
+	if s.dest.Kind() == reflect.Ptr {
+		p := reflect.New(t)
+		p.Elem().Set(rv)
+		s.dest.Set(p)
+	} else {
+		s.dest.Set(rv)
+	}
+	return nil
+}
+
+// jsonColumns reports which columns are mapped to a field with the ",json"
+// option, or nil if there are none.
+//
+// zdb only.
+func jsonColumns(m *reflectx.Mapper, t reflect.Type, columns []string) []bool {
+	tm := m.TypeMap(reflectx.Deref(t))
+	var j []bool
+	for i, c := range columns {
+		fi := tm.Paths[c]
+		if fi == nil {
+			continue
+		}
+		if _, ok := fi.Options["json"]; ok {
+			if j == nil {
+				j = make([]bool, len(columns))
+			}
+			j[i] = true
+		}
+	}
+	return j
+}
+
+// jsonScanner unmarshals a JSON column in to dest.
+//
+// zdb only.
+type jsonScanner struct{ dest reflect.Value }
+
+func (s *jsonScanner) Scan(src any) error {
+	var b []byte
+	switch ss := src.(type) {
+	case nil:
+		s.dest.SetZero()
+		return nil
+	case []byte:
+		b = ss
+	case string:
+		b = []byte(ss)
+	default:
+		return fmt.Errorf("can't unmarshal %T as JSON", src)
+	}
+
+	v := reflect.New(s.dest.Type())
+	err := json.Unmarshal(b, v.Interface())
+	if err != nil {
+		return err
+	}
+	s.dest.Set(v.Elem())
+	return nil
+}
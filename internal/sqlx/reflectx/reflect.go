@@ -78,7 +78,18 @@ func (m *Mapper) FieldMap(v reflect.Value) map[string]reflect.Value {
 	r := map[string]reflect.Value{}
 	tm := m.TypeMap(v.Type())
 	for tagName, fi := range tm.Names {
-		r[tagName] = FieldByIndexes(v, fi.Index)
+		// Don't allocate nil pointers like FieldByIndexes() does, as v may not
+		// be addressable. Fields inside a nil struct pointer are the zero
+		// value.
+		f := v
+		for _, i := range fi.Index {
+			if f.Kind() == reflect.Ptr && f.IsNil() {
+				f = fi.Zero
+				break
+			}
+			f = reflect.Indirect(f).Field(i)
+		}
+		r[tagName] = f
 	}
 	return r
 }
@@ -204,9 +215,10 @@ func mustBe(v kinder, expected reflect.Kind) {
 }
 
 type typeQueue struct {
-	t  reflect.Type
-	fi *FieldInfo
-	pp string // Parent path
+	t      reflect.Type
+	fi     *FieldInfo
+	pp     string // Parent path
+	prefix bool   // pp is a column prefix from the "prefix" option; zdb only.
 }
 
 // A copying append that creates a new slice each time.
@@ -281,7 +293,7 @@ func getMapping(t reflect.Type, tagName string, mapFunc mapf) *StructMap {
 
 	root := &FieldInfo{}
 	queue := []typeQueue{}
-	queue = append(queue, typeQueue{Deref(t), root, ""})
+	queue = append(queue, typeQueue{Deref(t), root, "", false})
 
 QueueLoop:
 	for len(queue) != 0 {
@@ -325,6 +337,8 @@ QueueLoop:
 			// if the path is empty this path is just the name
 			if tq.pp == "" {
 				fi.Path = fi.Name
+			} else if tq.prefix {
+				fi.Path = tq.pp + fi.Name
 			} else {
 				fi.Path = tq.pp + "." + fi.Name
 			}
@@ -349,11 +363,17 @@ QueueLoop:
 					nChildren = ft.NumField()
 				}
 				fi.Children = make([]*FieldInfo, nChildren)
-				queue = append(queue, typeQueue{Deref(f.Type), &fi, pp})
+				queue = append(queue, typeQueue{Deref(f.Type), &fi, pp, tq.prefix})
+			} else if _, ok := fi.Options["json"]; ok {
+				// JSON is stored in a single column; don't map the fields.
 			} else if fi.Zero.Kind() == reflect.Struct || (fi.Zero.Kind() == reflect.Ptr && fi.Zero.Type().Elem().Kind() == reflect.Struct) {
 				fi.Index = appendCopy(tq.fi.Index, fieldPos)
 				fi.Children = make([]*FieldInfo, Deref(f.Type).NumField())
-				queue = append(queue, typeQueue{Deref(f.Type), &fi, fi.Path})
+				if p, ok := fi.Options["prefix"]; ok {
+					queue = append(queue, typeQueue{Deref(f.Type), &fi, p, true})
+				} else {
+					queue = append(queue, typeQueue{Deref(f.Type), &fi, fi.Path, false})
+				}
 			}
 
 			fi.Index = appendCopy(tq.fi.Index, fieldPos)
@@ -293,6 +293,42 @@ func TestNestedStruct(t *testing.T) {
 }
 */
 
+func TestPrefix(t *testing.T) {
+	type Customer struct {
+		Name string `db:"name"`
+	}
+	type Order struct {
+		ID       int      `db:"id"`
+		Customer Customer `db:"customer,prefix=c_"`
+	}
+	type User struct {
+		ID    int   `db:"id"`
+		Order Order `db:"order,prefix=o_"`
+	}
+
+	m := NewMapper("db", nil)
+	u := User{ID: 1, Order: Order{ID: 2, Customer: Customer{Name: "x"}}}
+	uv := reflect.ValueOf(u)
+
+	tests := []struct {
+		name string
+		want any
+	}{
+		{"id", 1},
+		{"o_id", 2},
+		{"c_name", "x"},
+	}
+	for _, tt := range tests {
+		v := m.FieldByName(uv, tt.name)
+		if v.Interface() != tt.want {
+			t.Errorf("%s: want %v, got %v", tt.name, tt.want, v.Interface())
+		}
+	}
+	if v := m.FieldByName(uv, "order.id"); v.Type() != uv.Type() {
+		t.Errorf("order.id: expecting field to not exist")
+	}
+}
+
 /*
 func TestInlineStruct(t *testing.T) {
 	type Employee struct {
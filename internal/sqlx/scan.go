@@ -1,10 +1,13 @@
 package sqlx
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
+	"slices"
+	"strings"
 
 	"zgo.at/zdb/internal/sqlx/reflectx"
 )
@@ -38,6 +41,10 @@ func (r *Rows) StructScan(dest any) error {
 		m := r.Mapper
 
 		r.fields = m.TraversalsByName(v.Type(), columns)
+		if err := checkStrict(r.strict, m, v.Type(), columns, r.fields, dest); err != nil {
+			return err
+		}
+		r.json = jsonColumns(m, v.Type(), columns)
 		if f, err := missingFields(r.fields); err != nil {
 			missErr = &ErrMissingField{
 				Column: columns[f],
@@ -48,7 +55,7 @@ func (r *Rows) StructScan(dest any) error {
 		r.started = true
 	}
 
-	err := fieldsByTraversal(v, r.fields, r.values, true)
+	err := fieldsByTraversal(v, r.fields, r.json, r.values, true)
 	if err != nil {
 		return err
 	}
@@ -104,9 +111,18 @@ func (r *Row) scanAny(dest any, structOnly bool) error {
 	}
 
 	m := r.Mapper
+	if base.Kind() == reflect.Struct {
+		if nested := nestedSlices(m, base); len(nested) > 0 {
+			return r.scanNested(v, base, columns, nested)
+		}
+	}
 
 	var missErr error
 	fields := m.TraversalsByName(v.Type(), columns)
+	if err := checkStrict(r.strict, m, v.Type(), columns, fields, dest); err != nil {
+		return err
+	}
+	json := jsonColumns(m, v.Type(), columns)
 	if f, err := missingFields(fields); err != nil {
 		missErr = &ErrMissingField{
 			Column: columns[f],
@@ -116,7 +132,7 @@ func (r *Row) scanAny(dest any, structOnly bool) error {
 	}
 	values := make([]any, len(columns))
 
-	err = fieldsByTraversal(v, fields, values, true)
+	err = fieldsByTraversal(v, fields, json, values, true)
 	if err != nil {
 		return err
 	}
@@ -317,15 +333,24 @@ func scanAll(rows rowsi, dest any, structOnly bool) error {
 		var (
 			values []any
 			m      *reflectx.Mapper
+			strict Strict
 		)
 		switch r := rows.(type) {
 		case *Rows:
-			m = r.Mapper
+			m, strict = r.Mapper, r.strict
 		default:
 			m = mapper()
 		}
 
+		if nested := nestedSlices(m, base); len(nested) > 0 {
+			return scanNested(rows, dest, direct, base, isPtr, columns, m, nested, strict)
+		}
+
 		fields := m.TraversalsByName(base, columns)
+		if err := checkStrict(strict, m, base, columns, fields, dest); err != nil {
+			return err
+		}
+		json := jsonColumns(m, base, columns)
 		f, err := missingFields(fields)
 		if err != nil {
 			missErr = &ErrMissingField{
@@ -340,7 +365,7 @@ func scanAll(rows rowsi, dest any, structOnly bool) error {
 			vp = reflect.New(base)
 			v = reflect.Indirect(vp)
 
-			err = fieldsByTraversal(v, fields, values, true)
+			err = fieldsByTraversal(v, fields, json, values, true)
 			if err != nil {
 				return err
 			}
@@ -360,7 +385,7 @@ func scanAll(rows rowsi, dest any, structOnly bool) error {
 	} else {
 		for rows.Next() {
 			vp = reflect.New(base)
-			err = rows.Scan(vp.Interface())
+			err = rows.Scan(ScanDest(vp.Interface()))
 			if err != nil {
 				return err
 			}
@@ -407,13 +432,13 @@ func baseType(t reflect.Type, expected reflect.Kind) (reflect.Type, error) {
 
 // fieldsByName fills a values interface with fields from the passed value based
 // on the traversals in int. If ptrs is true, return addresses instead of
-// values.
+// values, and columns set in json get a scanner that unmarshals JSON.
 //
 // We write this instead of using FieldsByName to save allocations and map
 // lookups when iterating over many rows.  Empty traversals will get an
 // interface pointer. Because of the necessity of requesting ptrs or values,
 // it's considered a bit too specialized for inclusion in reflectx itself.
-func fieldsByTraversal(v reflect.Value, traversals [][]int, values []any, ptrs bool) error {
+func fieldsByTraversal(v reflect.Value, traversals [][]int, json []bool, values []any, ptrs bool) error {
 	v = reflect.Indirect(v)
 	if v.Kind() != reflect.Struct {
 		return errors.New("argument not a struct")
@@ -425,8 +450,10 @@ func fieldsByTraversal(v reflect.Value, traversals [][]int, values []any, ptrs b
 			continue
 		}
 		f := reflectx.FieldByIndexes(v, traversal)
-		if ptrs {
-			values[i] = f.Addr().Interface()
+		if ptrs && json != nil && json[i] {
+			values[i] = &jsonScanner{dest: f}
+		} else if ptrs {
+			values[i] = ScanDest(f.Addr().Interface())
 		} else {
 			values[i] = f.Interface()
 		}
@@ -442,3 +469,333 @@ func missingFields(transversals [][]int) (field int, err error) {
 	}
 	return 0, nil
 }
+
+// nestedSlice is a slice of structs with the "prefix" option, which is filled
+// from joined rows.
+//
+// zdb only.
+type nestedSlice struct {
+	index  []int        // Traversal to the slice field in the parent.
+	prefix string       // Column prefix.
+	elem   reflect.Type // Element type (without pointer).
+	isPtr  bool         // []*T rather than []T
+	id     []int        // Traversal to the ,id field in elem; nil if there is none.
+}
+
+// nestedSlices gets all slice fields with the "prefix" option in t.
+func nestedSlices(m *reflectx.Mapper, t reflect.Type) []nestedSlice {
+	var (
+		tm     = m.TypeMap(t)
+		nested []nestedSlice
+	)
+	for _, fi := range tm.Index {
+		p, ok := fi.Options["prefix"]
+		if !ok || fi.Field.Type.Kind() != reflect.Slice || !topLevel(tm, fi) {
+			continue
+		}
+		elem := reflectx.Deref(fi.Field.Type.Elem())
+		if elem.Kind() != reflect.Struct || isScannable(elem) {
+			continue
+		}
+
+		n := nestedSlice{
+			index:  fi.Index,
+			prefix: p,
+			elem:   elem,
+			isPtr:  fi.Field.Type.Elem().Kind() == reflect.Ptr,
+		}
+		if id := idField(m.TypeMap(elem)); id != nil {
+			n.id = id.Index
+		}
+		nested = append(nested, n)
+	}
+	return nested
+}
+
+// topLevel reports if fi is a field on the struct itself, or an embedded struct.
+func topLevel(tm *reflectx.StructMap, fi *reflectx.FieldInfo) bool {
+	for p := fi.Parent; p != nil && p != tm.Tree; p = p.Parent {
+		if !p.Embedded {
+			return false
+		}
+	}
+	return true
+}
+
+// idField gets the top-level field with the ",id" option.
+func idField(tm *reflectx.StructMap) *reflectx.FieldInfo {
+	for _, fi := range tm.Index {
+		if _, ok := fi.Options["id"]; ok && topLevel(tm, fi) {
+			return fi
+		}
+	}
+	return nil
+}
+
+// scanNested scans joined rows in to dest, which has one or more slices with
+// the "prefix" option.
+//
+// Rows are grouped by the ,id field of the parent, and the columns starting
+// with the prefix are appended to the slice. Rows where all the prefixed
+// columns are NULL (e.g. from a LEFT JOIN) are skipped. If the element type has
+// an ,id field then duplicates are skipped, which allows loading more than one
+// slice in a single query.
+//
+// zdb only.
+func scanNested(rows rowsi, dest any, direct reflect.Value, base reflect.Type, isPtr bool, columns []string, m *reflectx.Mapper, nested []nestedSlice, strict Strict) error {
+	tm := m.TypeMap(base)
+	parentID := idField(tm)
+	if parentID == nil {
+		return fmt.Errorf("scanning nested slices in %s: need a field with the ,id option", base)
+	}
+
+	var (
+		missErr error
+		// Index in nested, or -1 for the parent.
+		colNested = make([]int, len(columns))
+		fields    = make([][]int, len(columns))
+		isJSON    = make([]bool, len(columns))
+	)
+	for i, c := range columns {
+		colNested[i] = -1
+		for j, n := range nested {
+			if strings.HasPrefix(c, n.prefix) &&
+				(colNested[i] == -1 || len(n.prefix) > len(nested[colNested[i]].prefix)) {
+				colNested[i] = j
+			}
+		}
+
+		var fi *reflectx.FieldInfo
+		if colNested[i] == -1 {
+			fi = tm.Names[c]
+			// Prefixes are for the full column name, so a struct with a
+			// prefix inside the slice's element doesn't need to include the
+			// slice's prefix.
+			for j := 0; fi == nil && j < len(nested); j++ {
+				if fi = m.TypeMap(nested[j].elem).Names[c]; fi != nil {
+					colNested[i] = j
+				}
+			}
+		} else {
+			n := nested[colNested[i]]
+			fi = m.TypeMap(n.elem).Names[c[len(n.prefix):]]
+		}
+		if fi != nil {
+			fields[i] = fi.Index
+			_, isJSON[i] = fi.Options["json"]
+		} else if missErr == nil {
+			missErr = &ErrMissingField{Column: c, Type: fmt.Sprintf("%T", dest)}
+		}
+	}
+	// Only check columns; the fields are for different types.
+	if strict&StrictColumns != 0 && missErr != nil {
+		return missErr
+	}
+
+	var (
+		values       = make([]any, len(columns))
+		nestedValues = make([]any, len(columns))
+		parents      = make(map[any]int)
+		seenNested   = make([]map[[2]any]struct{}, len(nested))
+		nestedValid  = make([]bool, len(nested))
+	)
+	for i := range seenNested {
+		seenNested[i] = make(map[[2]any]struct{})
+	}
+	for rows.Next() {
+		vp := reflect.New(base)
+		v := vp.Elem()
+		for i := range columns {
+			if colNested[i] == -1 && fields[i] != nil && isJSON[i] {
+				values[i] = &jsonScanner{dest: reflectx.FieldByIndexes(v, fields[i])}
+			} else if colNested[i] == -1 && fields[i] != nil {
+				values[i] = ScanDest(reflectx.FieldByIndexes(v, fields[i]).Addr().Interface())
+			} else {
+				values[i] = new(any)
+			}
+		}
+		err := rows.Scan(values...)
+		if err != nil {
+			return err
+		}
+
+		key := mapKey(reflectx.FieldByIndexesReadOnly(v, parentID.Index).Interface())
+		pos, ok := parents[key]
+		if !ok {
+			pos = direct.Len()
+			parents[key] = pos
+			if isPtr {
+				direct.Set(reflect.Append(direct, vp))
+			} else {
+				direct.Set(reflect.Append(direct, v))
+			}
+		}
+		parent := reflect.Indirect(direct.Index(pos))
+
+		for j := range nestedValid {
+			nestedValid[j] = false
+		}
+		for i := range columns {
+			if colNested[i] > -1 && *values[i].(*any) != nil {
+				nestedValid[colNested[i]] = true
+			}
+		}
+		for j, n := range nested {
+			if !nestedValid[j] {
+				continue
+			}
+
+			// Scan the row again, now in to the element, so values are
+			// converted in the same way as for the parent.
+			ep := reflect.New(n.elem)
+			for i := range columns {
+				switch {
+				case colNested[i] != j || fields[i] == nil:
+					nestedValues[i] = skipScanner{}
+				case isJSON[i]:
+					nestedValues[i] = &jsonScanner{dest: reflectx.FieldByIndexes(ep.Elem(), fields[i])}
+				default:
+					nestedValues[i] = ScanDest(reflectx.FieldByIndexes(ep.Elem(), fields[i]).Addr().Interface())
+				}
+			}
+			err := rows.Scan(nestedValues...)
+			if err != nil {
+				return err
+			}
+			if n.id != nil {
+				k := [2]any{key, mapKey(reflectx.FieldByIndexesReadOnly(ep.Elem(), n.id).Interface())}
+				if _, ok := seenNested[j][k]; ok {
+					continue
+				}
+				seenNested[j][k] = struct{}{}
+			}
+
+			sl := reflectx.FieldByIndexes(parent, n.index)
+			if n.isPtr {
+				sl.Set(reflect.Append(sl, ep))
+			} else {
+				sl.Set(reflect.Append(sl, ep.Elem()))
+			}
+		}
+	}
+
+	err := rows.Err()
+	if err != nil {
+		return err
+	}
+	return missErr
+}
+
+// scanNested scans all rows for a single struct with nested slices; rows for
+// other parents than the first are ignored.
+//
+// zdb only.
+func (r *Row) scanNested(v reflect.Value, base reflect.Type, columns []string, nested []nestedSlice) error {
+	sl := reflect.New(reflect.SliceOf(base)).Elem()
+	err := scanNested(r.rows, v.Interface(), sl, base, false, columns, r.Mapper, nested, r.strict)
+	var missErr *ErrMissingField
+	if err != nil && !errors.As(err, &missErr) {
+		return err
+	}
+	if sl.Len() == 0 {
+		return sql.ErrNoRows
+	}
+
+	dest := v.Elem()
+	for dest.Kind() == reflect.Ptr {
+		if dest.IsNil() {
+			dest.Set(reflect.New(dest.Type().Elem()))
+		}
+		dest = dest.Elem()
+	}
+	dest.Set(sl.Index(0))
+	return err
+}
+
+// mapKey gets v as a value that can be used as a map key; IDs scanned from
+// blob columns are []byte, which can't be.
+//
+// zdb only.
+func mapKey(v any) any {
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	if v != nil && !reflect.TypeOf(v).Comparable() {
+		return fmt.Sprintf("%#v", v)
+	}
+	return v
+}
+
+// skipScanner ignores the value.
+//
+// zdb only.
+type skipScanner struct{}
+
+func (skipScanner) Scan(any) error { return nil }
+
+// Strict scanning modes.
+//
+// zdb only.
+type Strict uint8
+
+const (
+	StrictColumns Strict = 1 << iota // Error on columns without a struct field.
+	StrictFields                     // Error on struct fields without a column.
+)
+
+type strictKey struct{}
+
+// WithStrict sets the strict scanning mode for queries run with this context.
+//
+// zdb only.
+func WithStrict(ctx context.Context, s Strict) context.Context {
+	return context.WithValue(ctx, strictKey{}, s)
+}
+
+func strictFrom(ctx context.Context) Strict {
+	s, _ := ctx.Value(strictKey{}).(Strict)
+	return s
+}
+
+// ErrMissingColumn is used in strict mode if a struct field has no column.
+//
+// zdb only.
+type ErrMissingColumn struct{ Field, Type string }
+
+func (e ErrMissingColumn) Error() string {
+	return fmt.Sprintf("no column for field %q in type %s", e.Field, e.Type)
+}
+
+// checkStrict checks if the columns and struct fields match according to the
+// strict mode.
+//
+// zdb only.
+func checkStrict(s Strict, m *reflectx.Mapper, t reflect.Type, columns []string, fields [][]int, dest any) error {
+	if s&StrictColumns != 0 {
+		if f, err := missingFields(fields); err != nil {
+			return &ErrMissingField{Column: columns[f], Type: fmt.Sprintf("%T", dest)}
+		}
+	}
+	if s&StrictFields != 0 {
+		tm := m.TypeMap(reflectx.Deref(t))
+	outer:
+		for _, fi := range tm.Index {
+			if fi.Embedded || tm.Paths[fi.Path] != fi {
+				continue
+			}
+			// Fields in a struct which is scanned as a whole, or a struct of
+			// which some fields are scanned, are okay.
+			for _, f := range fields {
+				if hasPrefix(f, fi.Index) || hasPrefix(fi.Index, f) {
+					continue outer
+				}
+			}
+			return &ErrMissingColumn{Field: fi.Path, Type: fmt.Sprintf("%T", dest)}
+		}
+	}
+	return nil
+}
+
+func hasPrefix(s, prefix []int) bool {
+	return len(prefix) > 0 && len(s) >= len(prefix) && slices.Equal(s[:len(prefix)], prefix)
+}
@@ -50,7 +50,11 @@ func mapper() *reflectx.Mapper {
 //   - it is not a struct
 //   - it implements sql.Scanner
 //   - it has no exported fields
+//   - it has a decoder registered with RegisterDecoder()
 func isScannable(t reflect.Type) bool {
+	if decoder(t) != nil {
+		return true
+	}
 	if reflect.PtrTo(t).Implements(_scannerInterface) {
 		return true
 	}
@@ -86,6 +90,7 @@ type Row struct {
 	err    error
 	rows   *sql.Rows
 	Mapper *reflectx.Mapper
+	strict Strict
 }
 
 // Scan is a fixed implementation of sql.Row.Scan, which does not discard the
@@ -121,7 +126,7 @@ func (r *Row) Scan(dest ...any) error {
 		}
 		return sql.ErrNoRows
 	}
-	err := r.rows.Scan(dest...)
+	err := r.rows.Scan(ScanDests(dest)...)
 	if err != nil {
 		return err
 	}
@@ -245,7 +250,9 @@ type Rows struct {
 	// these fields cache memory use for a rows during iteration w/ structScan
 	started bool
 	fields  [][]int
+	json    []bool
 	values  []any
+	strict  Strict
 }
 
 // SliceScan using this Rows.
@@ -332,14 +339,14 @@ func (db *DB) QueryxContext(ctx context.Context, query string, args ...any) (*Ro
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{Rows: r, Mapper: db.Mapper}, err
+	return &Rows{Rows: r, Mapper: db.Mapper, strict: strictFrom(ctx)}, err
 }
 
 // QueryRowxContext queries the database and returns an *sqlx.Row.
 // Any placeholder parameters are replaced with supplied args.
 func (db *DB) QueryRowxContext(ctx context.Context, query string, args ...any) *Row {
 	rows, err := db.DB.QueryContext(ctx, query, args...)
-	return &Row{rows: rows, err: err, Mapper: db.Mapper}
+	return &Row{rows: rows, err: err, Mapper: db.Mapper, strict: strictFrom(ctx)}
 }
 
 // BeginTxx begins a transaction and returns an *sqlx.Tx instead of an
@@ -402,14 +409,14 @@ func (c *Conn) QueryxContext(ctx context.Context, query string, args ...any) (*R
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{Rows: r, Mapper: c.Mapper}, err
+	return &Rows{Rows: r, Mapper: c.Mapper, strict: strictFrom(ctx)}, err
 }
 
 // QueryRowxContext queries the database and returns an *sqlx.Row.
 // Any placeholder parameters are replaced with supplied args.
 func (c *Conn) QueryRowxContext(ctx context.Context, query string, args ...any) *Row {
 	rows, err := c.Conn.QueryContext(ctx, query, args...)
-	return &Row{rows: rows, err: err, Mapper: c.Mapper}
+	return &Row{rows: rows, err: err, Mapper: c.Mapper, strict: strictFrom(ctx)}
 }
 
 // Rebind a query within a Conn's bindvar type.
@@ -424,7 +431,7 @@ func (tx *Tx) QueryxContext(ctx context.Context, query string, args ...any) (*Ro
 	if err != nil {
 		return nil, err
 	}
-	return &Rows{Rows: r, Mapper: tx.Mapper}, err
+	return &Rows{Rows: r, Mapper: tx.Mapper, strict: strictFrom(ctx)}, err
 }
 
 // SelectContext within a transaction and context.
@@ -444,7 +451,7 @@ func (tx *Tx) GetContext(ctx context.Context, dest any, query string, args ...an
 // Any placeholder parameters are replaced with supplied args.
 func (tx *Tx) QueryRowxContext(ctx context.Context, query string, args ...any) *Row {
 	rows, err := tx.Tx.QueryContext(ctx, query, args...)
-	return &Row{rows: rows, err: err, Mapper: tx.Mapper}
+	return &Row{rows: rows, err: err, Mapper: tx.Mapper, strict: strictFrom(ctx)}
 }
 
 // NamedExec using this Tx.
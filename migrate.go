@@ -236,6 +236,90 @@ func (m Migrate) Run(which ...string) error {
 	return nil
 }
 
+// Rollback a migration that was run, or the last one that was run if name is
+// "".
+//
+// This runs the "down" migration from the migrate/down directory, which uses
+// the same names as migrations (e.g. migrate/down/2021-06-18-1-name.sql), and
+// removes the entry from the version table. Go migrations can't be rolled back.
+func (m Migrate) Rollback(name string) error {
+	_, ranMig, err := m.List()
+	if err != nil {
+		return fmt.Errorf("zdb.Migrate.Rollback: %w", err)
+	}
+	if name == "" {
+		if len(ranMig) == 0 {
+			return fmt.Errorf("zdb.Migrate.Rollback: no migrations were run")
+		}
+		name = ranMig[len(ranMig)-1]
+	}
+	version := zstring.TrimSuffixes(filepath.Base(name), ".sql", ".gotxt")
+	if !slices.Contains(ranMig, version) {
+		return fmt.Errorf("zdb.Migrate.Rollback: migration not run: %q", name)
+	}
+	if m.findGoMig(version) != nil {
+		return fmt.Errorf("zdb.Migrate.Rollback: %q is a Go migration", version)
+	}
+
+	b, file, err := findFile(m.files, insertDialect(m.db, "down/"+version)...)
+	if err != nil {
+		return fmt.Errorf("zdb.Migrate.Rollback: no down migration for %q: %w", version, err)
+	}
+	s := string(b)
+	if strings.HasSuffix(file, ".gotxt") {
+		b, err = Template(m.db.SQLDialect(), s)
+		if err != nil {
+			return fmt.Errorf("zdb.Migrate.Rollback: %q: %w", version, err)
+		}
+		s = string(b)
+	}
+
+	if m.show {
+		fmt.Println("-- " + file)
+		fmt.Println(strings.TrimRight(s, "\n"))
+		fmt.Println("\n-- Remove migration.")
+		fmt.Println(ApplyParams(`delete from version where name = ?`, version))
+		return nil
+	}
+	if m.log != nil {
+		msg := "rollback " + version
+		if m.test {
+			msg += " (test mode; not committing)"
+		}
+		m.log(msg)
+	}
+
+	ctx := WithDB(context.Background(), m.db)
+	if m.db.SQLDialect() == DialectSQLite {
+		err := Exec(ctx, `pragma foreign_keys = off`)
+		if err != nil {
+			return fmt.Errorf("zdb.Migrate.Rollback: %w", err)
+		}
+	}
+
+	ctx, tx, err := m.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("zdb.Migrate.Rollback: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = Exec(ctx, s)
+	if err != nil {
+		return fmt.Errorf("zdb.Migrate.Rollback: running %q: %w", file, err)
+	}
+	err = Exec(ctx, `delete from version where name = ?`, version)
+	if err != nil {
+		return fmt.Errorf("zdb.Migrate.Rollback: %w", err)
+	}
+	if !m.test {
+		err := tx.Commit()
+		if err != nil {
+			return fmt.Errorf("zdb.Migrate.Rollback: %w", err)
+		}
+	}
+	return nil
+}
+
 func (m Migrate) findGoMig(name string) func(context.Context) error {
 	for k, f := range m.gomig {
 		if k == name {
@@ -0,0 +1,465 @@
+package zdb
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"zgo.at/zdb/internal/sqltoken"
+)
+
+// Page is a page of results from [Paginate].
+type Page[T any] struct {
+	Rows []T
+	Next string // Cursor for the next page; empty if this is the last page.
+	Prev string // Cursor for the previous page; empty if this is the first page.
+}
+
+// Paginate selects a page of at most limit rows with keyset pagination.
+//
+// This adds a WHERE clause to query to select all rows after (or before) the
+// cursor, an ORDER BY for the columns in order, and a LIMIT. The query can't
+// have its own ORDER BY or LIMIT. For example:
+//
+//	page, err := zdb.Paginate[User](ctx,
+//	    `select * from users where site_id = :site`,
+//	    []string{"created_at", "user_id"}, cursor, 20,
+//	    map[string]any{"site": siteID})
+//
+// Will run:
+//
+//	select * from users where (site_id = :site) and (created_at, user_id) > (:zdb_cursor0, :zdb_cursor1)
+//	order by created_at, user_id limit 21
+//
+// Columns can have "desc" to sort in descending order, for example "created_at
+// desc". The column values must be NOT NULL, and the combination of all
+// columns must be unique; add a primary key as the last column if needed.
+//
+// The cursor is empty for the first page. The returned [Page] has the cursors
+// for the next and previous pages; these are opaque strings that can be passed
+// to Paginate() again with the same query and order.
+//
+// The columns in order must be in the result, and T must be a struct with the
+// columns as db tags. Columns can be qualified ("u.user_id"); the part after the
+// last "." is used as the field name.
+//
+// On PostgreSQL and SQLite a row value comparison is used if all columns have
+// the same direction; otherwise it's expanded to "a > ? or (a = ? and b > ?)",
+// which MariaDB can use indexes for.
+//
+// Parameters in the query need to be named parameters or "?" placeholders.
+func Paginate[T any](ctx context.Context, query string, order []string, cursor string, limit int, params ...any) (Page[T], error) {
+	var page Page[T]
+	if len(order) == 0 {
+		return page, errors.New("zdb.Paginate: no order columns")
+	}
+	if limit < 1 {
+		return page, fmt.Errorf("zdb.Paginate: invalid limit: %d", limit)
+	}
+
+	var (
+		cols = make([]string, len(order))
+		desc = make([]bool, len(order))
+	)
+	for i, o := range order {
+		f := strings.Fields(o)
+		switch {
+		case len(f) == 1:
+		case len(f) == 2 && strings.EqualFold(f[1], "asc"):
+		case len(f) == 2 && strings.EqualFold(f[1], "desc"):
+			desc[i] = true
+		default:
+			return page, fmt.Errorf("zdb.Paginate: invalid order column: %q", o)
+		}
+		cols[i] = f[0]
+	}
+
+	var (
+		prev bool
+		vals []any
+	)
+	if cursor != "" {
+		var err error
+		prev, vals, err = decodeCursor(cursor)
+		if err != nil {
+			return page, fmt.Errorf("zdb.Paginate: %w", err)
+		}
+		if len(vals) != len(cols) {
+			return page, fmt.Errorf("zdb.Paginate: cursor has %d values but there are %d order columns", len(vals), len(cols))
+		}
+	}
+
+	// Going back: reverse the order and the comparison, and reverse the result.
+	if prev {
+		for i := range desc {
+			desc[i] = !desc[i]
+		}
+	}
+
+	ob := make([]string, len(cols))
+	for i := range cols {
+		ob[i] = cols[i]
+		if desc[i] {
+			ob[i] += " desc"
+		}
+	}
+
+	query, params, err := paginateLoad(ctx, query, params)
+	if err != nil {
+		return page, fmt.Errorf("zdb.Paginate: %w", err)
+	}
+	q, params, err := paginateQuery(ctx, query, cols, desc, vals, params)
+	if err != nil {
+		return page, fmt.Errorf("zdb.Paginate: %w", err)
+	}
+	q += fmt.Sprintf(" order by %s limit %d", strings.Join(ob, ", "), limit+1)
+
+	err = Select(ctx, &page.Rows, q, params...)
+	if err != nil {
+		return page, fmt.Errorf("zdb.Paginate: %w", err)
+	}
+
+	more := len(page.Rows) > limit
+	if more {
+		page.Rows = page.Rows[:limit]
+	}
+	if prev {
+		slices.Reverse(page.Rows)
+	}
+	if len(page.Rows) == 0 {
+		return page, nil
+	}
+
+	// When going back there's always a next page, and when going forward
+	// there's a previous page if we started from a cursor.
+	if more || prev {
+		page.Next, err = encodeCursor(false, &page.Rows[len(page.Rows)-1], cols)
+		if err != nil {
+			return page, fmt.Errorf("zdb.Paginate: %w", err)
+		}
+	}
+	if (prev && more) || (!prev && cursor != "") {
+		page.Prev, err = encodeCursor(true, &page.Rows[0], cols)
+		if err != nil {
+			return page, fmt.Errorf("zdb.Paginate: %w", err)
+		}
+	}
+	return page, nil
+}
+
+// paginateLoad loads the query if it starts with "load:", and runs the
+// template and its conditionals, so that paginateQuery() can modify the query
+// text.
+func paginateLoad(ctx context.Context, query string, params []any) (string, []any, error) {
+	if !strings.HasPrefix(query, "load:") {
+		return query, params, nil
+	}
+
+	db, name := MustGetDB(ctx), query[5:]
+	query, isTpl, err := loadImpl(db, name)
+	if err != nil || !isTpl {
+		return query, params, err
+	}
+
+	merged, named, _, _, err := prepareParams(params)
+	if err != nil {
+		return "", nil, err
+	}
+	query, err = queryTemplate(db, name, query, merged)
+	if err != nil {
+		return "", nil, err
+	}
+	query, merged, err = replaceConditionals(query, merged, tplCondDelims)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// Parameters for "?" in text that's not included are removed; keep the
+	// parameters that aren't sent to the database.
+	if pos, ok := merged.([]any); ok && !named {
+		for _, p := range params {
+			switch p.(type) {
+			case DumpArg, Strict, io.Writer:
+				pos = append(pos, p)
+			}
+		}
+		params = pos
+	}
+	return query, params, nil
+}
+
+// paginateQuery adds the where clause for the cursor to query.
+func paginateQuery(ctx context.Context, query string, cols []string, desc []bool, vals []any, params []any) (string, []any, error) {
+	var (
+		tokens         = sqltoken.Tokenize(query, paginateConfig(SQLDialect(ctx)))
+		depth          int
+		where, end     = -1, len(tokens)
+		positionalArgs int
+	)
+	for i, t := range tokens {
+		switch t.Type {
+		case sqltoken.Punctuation:
+			depth += strings.Count(t.Text, "(") - strings.Count(t.Text, ")")
+		case sqltoken.DollarNumber:
+			return "", nil, errors.New("can't use $n parameters; use ? or named parameters")
+		case sqltoken.QuestionMark:
+			if end == len(tokens) {
+				positionalArgs++
+			}
+		case sqltoken.Word:
+			if depth > 0 {
+				continue
+			}
+			switch strings.ToLower(t.Text) {
+			case "where":
+				if where == -1 {
+					where = i
+				}
+			case "group", "having", "window", "for":
+				if end == len(tokens) {
+					end = i
+				}
+			case "order", "limit", "offset", "fetch":
+				return "", nil, fmt.Errorf("query can't have %q", t.Text)
+			case "union", "intersect", "except":
+				return "", nil, fmt.Errorf("query can't have %q; use a subquery", t.Text)
+			}
+		}
+	}
+	if len(vals) == 0 {
+		return tokenText(tokens), params, nil
+	}
+
+	_, named, _, _, err := prepareParams(params)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var (
+		newParams = make([]any, 0, len(vals))
+		cparams   = make(map[string]any)
+		ph        = make([]string, len(cols))
+	)
+	for i := range cols {
+		if named {
+			n := "zdb_cursor" + strconv.Itoa(i)
+			cparams[n] = vals[i]
+			ph[i] = ":" + n
+		} else {
+			ph[i] = "?"
+		}
+	}
+
+	var cond strings.Builder
+	if SQLDialect(ctx) != DialectMariaDB && !slices.Contains(desc, !desc[0]) {
+		cmp := " > "
+		if desc[0] {
+			cmp = " < "
+		}
+		cond.WriteString("(" + strings.Join(cols, ", ") + ")" + cmp + "(" + strings.Join(ph, ", ") + ")")
+		newParams = append(newParams, vals...)
+	} else {
+		// a > ? or (a = ? and b > ?) or (a = ? and b = ? and c > ?)
+		cond.WriteByte('(')
+		for i := range cols {
+			if i > 0 {
+				cond.WriteString(" or ")
+			}
+			cond.WriteByte('(')
+			for j := 0; j < i; j++ {
+				cond.WriteString(cols[j] + " = " + ph[j] + " and ")
+				newParams = append(newParams, vals[j])
+			}
+			if desc[i] {
+				cond.WriteString(cols[i] + " < " + ph[i])
+			} else {
+				cond.WriteString(cols[i] + " > " + ph[i])
+			}
+			newParams = append(newParams, vals[i])
+			cond.WriteByte(')')
+		}
+		cond.WriteByte(')')
+	}
+
+	var b strings.Builder
+	if where > -1 {
+		b.WriteString(tokens[:where+1].String())
+		b.WriteString(" (")
+		b.WriteString(tokenText(tokens[where+1 : end]))
+		b.WriteString(") and ")
+	} else {
+		b.WriteString(tokenText(tokens[:end]))
+		b.WriteString(" where ")
+	}
+	b.WriteString(cond.String())
+	if end < len(tokens) {
+		b.WriteByte(' ')
+		b.WriteString(tokenText(tokens[end:]))
+	}
+
+	if named {
+		return b.String(), append(slices.Clone(params), cparams), nil
+	}
+	// Insert the parameters at the right position.
+	if positionalArgs > len(params) {
+		positionalArgs = len(params)
+	}
+	p := slices.Concat(params[:positionalArgs], newParams, params[positionalArgs:])
+	return b.String(), p, nil
+}
+
+func paginateConfig(dialect Dialect) sqltoken.Config {
+	c := tokenConfig(dialect)
+	c.NoticeQuestionMark = true
+	c.NoticeColonWord = true
+	return c
+}
+
+// tokenText gets the text of ts without leading and trailing whitespace. A
+// comment to the end of the line is kept on its own line, so that text added
+// after it isn't commented out.
+func tokenText(ts sqltoken.Tokens) string {
+	s := strings.TrimSpace(ts.String())
+	for i := len(ts) - 1; i >= 0; i-- {
+		if ts[i].Type == sqltoken.Whitespace {
+			continue
+		}
+		if ts[i].Type == sqltoken.Comment && !strings.HasPrefix(ts[i].Text, "/*") {
+			s += "\n"
+		}
+		break
+	}
+	return s
+}
+
+type cursorValue struct {
+	T string `json:"t"`
+	V any    `json:"v"`
+}
+
+func encodeCursor(prev bool, row any, cols []string) (string, error) {
+	c := struct {
+		Prev bool          `json:"p,omitempty"`
+		Vals []cursorValue `json:"v"`
+	}{prev, make([]cursorValue, 0, len(cols))}
+
+	for _, col := range cols {
+		if i := strings.LastIndexByte(col, '.'); i > -1 {
+			col = col[i+1:]
+		}
+		f := fieldByColumn(row, col)
+		if !f.IsValid() {
+			return "", fmt.Errorf("no field for order column %q", col)
+		}
+
+		v := f.Interface()
+		if vv, ok := v.(driver.Valuer); ok {
+			var err error
+			v, err = vv.Value()
+			if err != nil {
+				return "", err
+			}
+		}
+		rv := reflect.ValueOf(v)
+		switch {
+		case v == nil:
+			return "", fmt.Errorf("order column %q is NULL", col)
+		case rv.CanInt():
+			c.Vals = append(c.Vals, cursorValue{"i", strconv.FormatInt(rv.Int(), 10)})
+		case rv.CanUint():
+			c.Vals = append(c.Vals, cursorValue{"u", strconv.FormatUint(rv.Uint(), 10)})
+		case rv.CanFloat():
+			c.Vals = append(c.Vals, cursorValue{"f", rv.Float()})
+		case rv.Kind() == reflect.String:
+			c.Vals = append(c.Vals, cursorValue{"s", rv.String()})
+		case rv.Kind() == reflect.Bool:
+			c.Vals = append(c.Vals, cursorValue{"b", rv.Bool()})
+		default:
+			switch vv := v.(type) {
+			case time.Time:
+				c.Vals = append(c.Vals, cursorValue{"t", vv.Format(time.RFC3339Nano)})
+			case []byte:
+				c.Vals = append(c.Vals, cursorValue{"y", vv})
+			default:
+				return "", fmt.Errorf("order column %q: unsupported type %T", col, v)
+			}
+		}
+	}
+
+	j, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(j), nil
+}
+
+func decodeCursor(cursor string) (bool, []any, error) {
+	j, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return false, nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var c struct {
+		Prev bool `json:"p"`
+		Vals []struct {
+			T string          `json:"t"`
+			V json.RawMessage `json:"v"`
+		} `json:"v"`
+	}
+	err = json.Unmarshal(j, &c)
+	if err != nil {
+		return false, nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+
+	vals := make([]any, 0, len(c.Vals))
+	for _, v := range c.Vals {
+		var (
+			val any
+			s   string
+		)
+		switch v.T {
+		case "i", "u", "s", "t":
+			err = json.Unmarshal(v.V, &s)
+			if err != nil {
+				break
+			}
+			switch v.T {
+			case "i":
+				val, err = strconv.ParseInt(s, 10, 64)
+			case "u":
+				val, err = strconv.ParseUint(s, 10, 64)
+			case "s":
+				val = s
+			case "t":
+				val, err = time.Parse(time.RFC3339Nano, s)
+			}
+		case "f":
+			var f float64
+			err = json.Unmarshal(v.V, &f)
+			val = f
+		case "b":
+			var b bool
+			err = json.Unmarshal(v.V, &b)
+			val = b
+		case "y":
+			var b []byte
+			err = json.Unmarshal(v.V, &b)
+			val = b
+		default:
+			err = fmt.Errorf("unknown type %q", v.T)
+		}
+		if err != nil {
+			return false, nil, fmt.Errorf("invalid cursor: %w", err)
+		}
+		vals = append(vals, val)
+	}
+	return c.Prev, vals, nil
+}
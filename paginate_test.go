@@ -0,0 +1,105 @@
+package zdb
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"zgo.at/zstd/ztest"
+)
+
+func TestPaginateQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		cols   []string
+		desc   []bool
+		vals   []any
+		params []any
+
+		want, wantErr string
+	}{
+		{`select * from t`, []string{"a"}, []bool{false}, nil, nil,
+			`select * from t []`, ""},
+		{`select * from t`, []string{"a", "b"}, []bool{false, false}, []any{1, 2}, nil,
+			`select * from t where (a, b) > (?, ?) [1 2]`, ""},
+		{`select * from t`, []string{"a", "b"}, []bool{true, true}, []any{1, 2}, nil,
+			`select * from t where (a, b) < (?, ?) [1 2]`, ""},
+		{`select * from t`, []string{"a", "b"}, []bool{false, true}, []any{1, 2}, nil,
+			`select * from t where ((a > ?) or (a = ? and b < ?)) [1 1 2]`, ""},
+
+		// Existing where, with group by, comments, and subqueries.
+		{`select * from t where x = ? or y in (select y from u where z = 1) -- comment`,
+			[]string{"a"}, []bool{false}, []any{1}, []any{"x"},
+			"select * from t where (x = ? or y in (select y from u where z = 1) -- comment\n) and (a) > (?) [x 1]", ""},
+		{"select * from t -- comment", []string{"a"}, []bool{false}, []any{1}, nil,
+			"select * from t -- comment\n where (a) > (?) [1]", ""},
+		{"select * from t -- comment", []string{"a"}, []bool{false}, nil, nil,
+			"select * from t -- comment\n []", ""},
+		// # is an operator on PostgreSQL, not a comment.
+		{"select * from t where data #> '{a}' = ? group by a", []string{"a"}, []bool{false}, []any{1}, []any{"x"},
+			"select * from t where (data #> '{a}' = ?) and (a) > (?) group by a [x 1]", ""},
+		{`select a, count(*) from t where x = ? group by a having count(*) > ?`,
+			[]string{"a"}, []bool{false}, []any{1}, []any{"x", 2},
+			`select a, count(*) from t where (x = ?) and (a) > (?) group by a having count(*) > ? [x 1 2]`, ""},
+		{"select a from t\ngroup by a",
+			[]string{"a"}, []bool{false}, []any{1}, nil,
+			`select a from t where (a) > (?) group by a [1]`, ""},
+
+		// Named
+		{`select * from t where x = :x`, []string{"a"}, []bool{false}, []any{1}, []any{map[string]any{"x": 1}},
+			`select * from t where (x = :x) and (a) > (:zdb_cursor0) [map[x:1] map[zdb_cursor0:1]]`, ""},
+
+		// Errors
+		{`select * from t order by a`, []string{"a"}, []bool{false}, nil, nil,
+			``, `query can't have "order"`},
+		{`select * from t union select * from u`, []string{"a"}, []bool{false}, nil, nil,
+			``, `query can't have "union"`},
+		{`select * from t where x = $1`, []string{"a"}, []bool{false}, nil, nil,
+			``, `can't use $n parameters`},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			ctx := testdriver(t)
+			q, p, err := paginateQuery(ctx, tt.query, tt.cols, tt.desc, tt.vals, tt.params)
+			if !ztest.ErrorContains(err, tt.wantErr) {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+			if have := fmt.Sprintf("%s %v", q, p); have != tt.want {
+				t.Errorf("\nhave: %s\nwant: %s", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor(t *testing.T) {
+	row := struct {
+		A int       `db:"a"`
+		B string    `db:"b"`
+		C time.Time `db:"c"`
+		D float64   `db:"d"`
+		E []byte    `db:"e"`
+	}{-42, "x", time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC), 1.5, []byte("y")}
+
+	c, err := encodeCursor(true, &row, []string{"a", "t.b", "c", "d", "e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, vals, err := decodeCursor(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	have := fmt.Sprintf("%t %#v", prev, vals)
+	want := `true []interface {}{-42, "x", time.Date(2020, time.January, 2, 3, 4, 5, 6, time.UTC), 1.5, []uint8{0x79}}`
+	if have != want {
+		t.Errorf("\nhave: %s\nwant: %s", have, want)
+	}
+
+	_, _, err = decodeCursor("not a cursor")
+	if !ztest.ErrorContains(err, "invalid cursor") {
+		t.Error(err)
+	}
+}
@@ -8,12 +8,14 @@ import (
 	"fmt"
 	"io"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"zgo.at/zdb/internal/sqltoken"
 	"zgo.at/zdb/internal/sqlx"
 	"zgo.at/zdb/internal/sqlx/reflectx"
-	"zgo.at/zstd/zstring"
 )
 
 // "Prepare" a query; unlike e.g. sqlx this is always done, and always done
@@ -21,10 +23,12 @@ import (
 //
 // 1. Extract the DumpArgs out of params.
 // 2. Load from filesystem if the query starts with "load:".
-// 3. Replace simple conditionals.
-// 4. Bind named parameters ("sqlx.Named()").
-// 5. Expand slices to multiple parameters ("sqlx.In()").
-// 6. Rebind to use the correct placeholder ("sqlx.Rebind()").
+// 3. Check for unused parameters if ConnectOptions.LintParams is set.
+// 4. Run text/template for .gotxt files, and replace simple conditionals.
+// 5. Bind named parameters ("sqlx.Named()").
+// 6. Convert types registered with RegisterType().
+// 7. Expand slices to multiple parameters ("sqlx.In()").
+// 8. Rebind to use the correct placeholder ("sqlx.Rebind()").
 //
 // I don't see any good reason to not just automatically do it, except to save
 // dozens to hundreds of ns per query; that said, we should be a bit smarter
@@ -39,25 +43,43 @@ func prepareImpl(ctx context.Context, db DB, query string, params ...any) (strin
 		return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
 	}
 
-	var isTpl bool
+	var (
+		isTpl bool
+		name  string
+	)
 	if strings.HasPrefix(query, "load:") {
-		query, isTpl, err = loadImpl(db, query[5:])
+		name = query[5:]
+		query, isTpl, err = loadImpl(db, name)
 		if err != nil {
 			return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
 		}
 	}
 
-	if isTpl {
-		q, err := Template(db.SQLDialect(), query, merged)
-		if err != nil {
-			return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
+	if named {
+		if l, ok := Unwrap(db).(interface{ lintParams() bool }); ok && l.lintParams() {
+			err := unusedParams(query, params)
+			if err != nil {
+				return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
+			}
 		}
-		query = string(q)
-	} else if named {
-		query, err = replaceConditionals(query, merged)
+	}
+
+	delims := condDelims
+	if isTpl {
+		query, err = queryTemplate(db, name, query, merged)
 		if err != nil {
 			return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
 		}
+		delims = tplCondDelims
+	}
+	query, merged, err = replaceConditionals(query, merged, delims)
+	if err != nil {
+		return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
+	}
+
+	merged, err = expandIdents(identQuote(db), merged)
+	if err != nil {
+		return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
 	}
 
 	// Sprintf SQL(..) strings in the query; do this before we process any other
@@ -78,11 +100,9 @@ func prepareImpl(ctx context.Context, db DB, query string, params ...any) (strin
 
 	qparams, ok := merged.([]any)
 	if ok {
-		for _, p := range qparams {
-			if _, ok := p.(SQL); ok {
-				// TODO: should fix
-				return "", nil, fmt.Errorf("zdb.Prepare: SQL() strings can only be used as named parameters")
-			}
+		query, qparams, err = replaceSQLParams(query, qparams)
+		if err != nil {
+			return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
 		}
 	}
 
@@ -94,6 +114,11 @@ func prepareImpl(ctx context.Context, db DB, query string, params ...any) (strin
 		}
 	}
 
+	qparams, err = encodeParams(db.SQLDialect(), qparams)
+	if err != nil {
+		return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
+	}
+
 	query, qparams, err = sqlx.In(query, qparams...)
 	if err != nil {
 		return "", nil, fmt.Errorf("zdb.Prepare: %w", err)
@@ -114,6 +139,7 @@ func prepareImpl(ctx context.Context, db DB, query string, params ...any) (strin
 //
 //   - Multiple named parameters are merged in a single map.
 //   - DumpArgs are removed.
+//   - Strict is removed.
 //   - Any io.Writer is removed.
 //
 // TODO: document that you can pass a io.Writer.
@@ -138,6 +164,13 @@ func prepareParams(params []any) (any, bool, DumpArg, io.Writer, error) {
 			dumpArgs |= d
 			continue
 		}
+		if _, ok := param.(Strict); ok { // Handled in withStrict()
+			continue
+		}
+		if _, ok := param.(Order); ok {
+			mergedPos = append(mergedPos, param)
+			continue
+		}
 		// TODO: maybe restrict this a bit more? What if you're passing a type
 		// which satisfies this interface?
 		if d, ok := param.(io.Writer); ok {
@@ -147,9 +180,9 @@ func prepareParams(params []any) (any, bool, DumpArg, io.Writer, error) {
 
 		t := typeOfElem(param)
 
-		// If this implements Value() then we never want to merge it with other
-		// structs or maps.
-		if t.Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) {
+		// If this implements Value() or is registered with RegisterType() then
+		// we never want to merge it with other structs or maps.
+		if t.Implements(reflect.TypeOf((*driver.Valuer)(nil)).Elem()) || encoder(t) != nil {
 			mergedPos = append(mergedPos, param)
 			continue
 		}
@@ -181,12 +214,25 @@ func prepareParams(params []any) (any, bool, DumpArg, io.Writer, error) {
 			}
 
 			named = true
-			m := reflectx.NewMapper("db", sqlx.NameMapper).FieldMap(reflect.ValueOf(param))
+			var (
+				mapper = reflectx.NewMapper("db", sqlx.NameMapper)
+				m      = mapper.FieldMap(reflect.ValueOf(param))
+				tm     = mapper.TypeMap(t)
+			)
 			for k, v := range m {
 				if _, ok := mergedNamed[k]; ok {
 					return nil, false, 0, nil, fmt.Errorf("parameter given more than once: %q", k)
 				}
 				mergedNamed[k] = v.Interface()
+				if fi := tm.Paths[k]; fi != nil {
+					if _, ok := fi.Options["json"]; ok {
+						j, err := jsonParam(mergedNamed[k])
+						if err != nil {
+							return nil, false, 0, nil, fmt.Errorf("parameter %q: %w", k, err)
+						}
+						mergedNamed[k] = j
+					}
+				}
 			}
 		}
 	}
@@ -228,132 +274,113 @@ func isNamed(t reflect.Type, a any) bool {
 	return t.Kind() == reflect.Struct || t.Kind() == reflect.Map
 }
 
-// TODO: it would be nice if this would deal with whitespace a bit better.
-//
-// This has two spaces in the resulting SQL:
-//
-//	"where {{:x x = :x}} order by a" → "where  order by a"
-//
-// And with newlines it's even worse:
-//
-//	    where
-//		     {{:x x = :x}}
-//		   order by a
-//	  →
-//		   where
-//
-//		   order by a
-func replaceConditionals(query string, params ...any) (string, error) {
-	for _, p := range zstring.IndexPairs(query, "{{:", "}}") {
-		s := p[0]
-		e := p[1]
-
-		name := query[s+3 : e]
-		i := strings.IndexAny(name, " \t\n")
-		if i == -1 {
-			continue
+// unusedParams returns an error if any of the keys in map parameters aren't
+// used in the query, either as a named parameter or in a conditional.
+func unusedParams(query string, params []any) error {
+	used := make(map[string]struct{})
+	for _, t := range sqltoken.Tokenize(query, sqltoken.Config{NoticeColonWord: true}) {
+		if t.Type == sqltoken.ColonWord {
+			used[t.Text[1:]] = struct{}{}
 		}
-		name = name[:i]
+	}
 
-		negate := strings.HasSuffix(name, "!")
-		if negate {
-			name = name[:len(name)-1]
+	var (
+		unused []string
+		m      map[string]any
+	)
+	for _, p := range params {
+		if p == nil {
+			continue
 		}
-
-		found := false
-		for _, param := range params {
-			// This is a bit inefficient, since it duplicates sqlx's NamedMapper
-			// logic; still seems plenty fast enough though.
-			include, has, err := includeConditional(param, name)
-			if err != nil {
-				return "", err
-			}
-			if !has {
-				continue
-			}
-			found = true
-			if negate {
-				include = !include
-			}
-			if include {
-				query = query[:s] + query[s+i+4:]     // Everything except "{{:word"
-				query = query[:e-i-4] + query[e-i-2:] // Everything except "}}"
-			} else {
-				query = query[:s] + query[e+2:]
-			}
-			if !found {
-				return "", fmt.Errorf("found not find %q for conditional", name)
+		v := reflect.ValueOf(p)
+		for v.Kind() == reflect.Ptr && !v.IsNil() {
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Map || !v.Type().ConvertibleTo(reflect.TypeOf(m)) {
+			continue
+		}
+		for k := range v.Convert(reflect.TypeOf(m)).Interface().(map[string]any) {
+			if _, ok := used[k]; !ok {
+				unused = append(unused, k)
 			}
 		}
 	}
-	return query, nil
-}
-
-// TODO: we can simplify this a bit if we just always convert struct to map in
-// prepareParams.
-func includeConditional(param any, name string) (include, has bool, err error) {
-	v := reflect.ValueOf(param)
-	for v.Kind() == reflect.Ptr {
-		v = v.Elem()
+	if len(unused) > 0 {
+		slices.Sort(unused)
+		return fmt.Errorf("unused parameters: %s", quoteList(unused))
 	}
+	return nil
+}
 
-	// Map
-	var m map[string]any
-	if v.Type().ConvertibleTo(reflect.TypeOf(m)) {
-		m = v.Convert(reflect.TypeOf(m)).Interface().(map[string]any)
-	}
-	if m != nil {
-		v, ok := m[name]
-		if !ok {
-			return false, false, nil
-		}
-		include, err := isTruthy(name, v)
-		return include, true, err
+// replaceSQLParams replaces the placeholders for positional SQL() parameters
+// with the SQL string, and removes them from params. The placeholders for the
+// remaining parameters are renumbered for $n.
+func replaceSQLParams(query string, params []any) (string, []any, error) {
+	if !slices.ContainsFunc(params, func(p any) bool { _, ok := p.(SQL); return ok }) {
+		return query, params, nil
 	}
 
-	// Struct
-	if v.Kind() == reflect.Struct {
-		c := reflectx.NewMapper("db", sqlx.NameMapper).FieldByName(v, name)
-		if c.Type() == v.Type() { // FieldByName() returns original struct if it's not found.
-			return false, false, nil
+	var (
+		b      = make([]byte, 0, len(query)+16)
+		keep   = make([]any, 0, len(params))
+		newPos = make([]int, len(params)) // Index in keep, or -1 for SQL.
+		tokens = sqltoken.Tokenize(query, sqltoken.Config{
+			NoticeQuestionMark: true,
+			NoticeDollarNumber: true,
+			NoticeDollarQuotes: true,
+		})
+		qmark, dollar bool
+	)
+	for i, p := range params {
+		if _, ok := p.(SQL); ok {
+			newPos[i] = -1
+			continue
 		}
-		include, err := isTruthy(name, c.Interface())
-		return include, true, err
+		newPos[i] = len(keep)
+		keep = append(keep, p)
 	}
 
-	return false, false, nil
-}
+	var n int
+	for _, t := range tokens {
+		switch t.Type {
+		default:
+			b = append(b, t.Text...)
 
-func isTruthy(name string, cond any) (bool, error) {
-	t := reflect.TypeOf(cond)
-	v := reflect.ValueOf(cond)
-	switch t.Kind() {
-	case reflect.Pointer:
-		if v.IsNil() {
-			return false, nil
-		}
-		return isTruthy(name, v.Elem().Interface())
-	case reflect.Bool:
-		return v.Bool(), nil
-	case reflect.String, reflect.Array, reflect.Slice:
-		return v.Len() > 0, nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return v.Int() > 0, nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return v.Uint() > 0, nil
-	default:
-		switch c := cond.(type) {
-		case time.Time:
-			return !c.IsZero(), nil
+		case sqltoken.QuestionMark:
+			qmark = true
+			if n < len(params) {
+				if s, ok := params[n].(SQL); ok {
+					t.Text = string(s)
+				}
+			}
+			b = append(b, t.Text...)
+			n++
+
+		case sqltoken.DollarNumber:
+			dollar = true
+			i, err := strconv.Atoi(t.Text[1:])
+			if err != nil || i < 1 || i > len(params) {
+				b = append(b, t.Text...)
+				continue
+			}
+			if s, ok := params[i-1].(SQL); ok {
+				b = append(b, s...)
+			} else {
+				b = append(b, '$')
+				b = strconv.AppendInt(b, int64(newPos[i-1]+1), 10)
+			}
 		}
-		return false, fmt.Errorf("unsupported conditional type %T for %q", cond, name)
 	}
-}
 
-func replaceParam(query string, n int, param SQL) (string, error) {
-	i := zstring.IndexN(query, "?", uint(n+1))
-	if i == -1 {
-		return "", fmt.Errorf("not enough parameters")
+	if qmark && dollar {
+		return "", nil, errors.New("SQL() parameters: can't mix ? and $n placeholders")
+	}
+	if !dollar {
+		for i := n; i < len(params); i++ {
+			if _, ok := params[i].(SQL); ok {
+				return "", nil, fmt.Errorf("SQL() parameter %d: no placeholder", i+1)
+			}
+		}
 	}
-	return query[:i] + string(param) + query[i+1:], nil
+	return string(b), keep, nil
 }
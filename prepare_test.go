@@ -92,30 +92,30 @@ func TestPrepare(t *testing.T) {
 
 		// Negation with !
 		{`select {{:yyy! cond}} where 1=1`, []any{map[string]any{"yyy": true}},
-			`select  where 1=1`, []any{}, ""},
+			`select where 1=1`, []any{}, ""},
 		// Negation with !
 		{`select {{:yyy! cond}} where 1=1`, []any{map[string]any{"yyy": false}},
 			`select cond where 1=1`, []any{}, ""},
 
 		// False conditional from bool
 		{`select {{:yyy cond}} where 1=1`, []any{map[string]any{"yyy": false}},
-			`select  where 1=1`, []any{}, ""},
+			`select where 1=1`, []any{}, ""},
 		{`select {{:yyy cond}} where 1=1`, []any{struct{ YYY bool }{false}},
-			`select  where 1=1`, []any{}, ""},
+			`select where 1=1`, []any{}, ""},
 		{`select {{:yyy cond}} where 1=1`, []any{map[string]any{"a": false}, struct{ YYY bool }{false}},
-			`select  where 1=1`, []any{}, ""},
+			`select where 1=1`, []any{}, ""},
 
 		// Multiple conditionals
 		{`select {{:a cond}} {{:b cond2}} `, []any{map[string]any{"a": true, "b": true}},
 			`select cond cond2 `, []any{}, ""},
 		{`select {{:a cond}} {{:b cond2}} `, []any{map[string]any{"a": false, "b": false}},
-			`select   `, []any{}, ""},
+			`select`, []any{}, ""},
 
 		// Parameters inside conditionals
 		{`select {{:a x like :foo}} {{:b y = :bar}}`, []any{map[string]any{"foo": "qwe", "bar": "zxc", "a": true, "b": true}},
 			`select x like $1 y = $2`, []any{"qwe", "zxc"}, ""},
 		{`select {{:a x like :foo}} {{:b y = :bar}}`, []any{map[string]any{"foo": "qwe", "bar": "zxc", "a": false, "b": true}},
-			`select  y = $1`, []any{"zxc"}, ""},
+			`select y = $1`, []any{"zxc"}, ""},
 
 		// Multiple conflicting params
 		{`select :x`, []any{map[string]any{"x": 1}, map[string]any{"x": 2}},
@@ -129,11 +129,17 @@ func TestPrepare(t *testing.T) {
 
 		// Conditional not found
 		{`select {{:x cond}}`, []any{map[string]any{"z": 1}},
-			``, nil, "could not find"},
-
-		// Condtional with positional
+			``, nil, `unknown conditional parameter: "x"`},
+		{`select {{:x cond}} {{:y cond {{:x cond}}}} {{:z cond}}`, []any{map[string]any{"z": 1}},
+			``, nil, `unknown conditional parameters: "x", "y"`},
+
+		// Named conditional without parameters or with positional parameters.
+		{`select 1 {{:x and x = 1}}`, nil,
+			``, nil, `unknown conditional parameter: "x"`},
+		{`select 1 {{:x and x = 1}}`, []any{1},
+			``, nil, `unknown conditional parameter: "x"`},
 		{`select {{:x cond}}`, []any{"z", 1},
-			`select {{:x cond}}`, []any{"z", 1}, ""},
+			``, nil, `unknown conditional parameter: "x"`},
 
 		// Invalid syntax for conditional; just leave it alone
 		{`select {{cond}}`, []any{map[string]any{"yyy": false}},
@@ -176,6 +182,60 @@ func TestPrepare(t *testing.T) {
 			`select false $1`, []any{(*string)(nil)}, ""},
 		{`select {{:x true :x}}{{:x! false :x}}`, []any{map[string]any{"x": ptr2}},
 			`select false $1`, []any{ptr2}, ""},
+
+		// Nested
+		{`select {{:a x {{:b y}} z}}`, []any{map[string]any{"a": true, "b": true}},
+			`select x y z`, []any{}, ""},
+		{`select {{:a x {{:b y}} z}}`, []any{map[string]any{"a": true, "b": false}},
+			`select x z`, []any{}, ""},
+		{`select {{:a x {{:b y}} z}} from t`, []any{map[string]any{"a": false, "b": true}},
+			`select from t`, []any{}, ""},
+		{`select {{:a x {{:b y = :y}}}} from t`, []any{map[string]any{"a": true, "b": true, "y": 1}},
+			`select x y = $1 from t`, []any{1}, ""},
+		{`select {{:a x`, []any{map[string]any{"a": true}},
+			``, nil, `unclosed conditional "a"`},
+
+		// Else
+		{`select {{:a x || y}}`, []any{map[string]any{"a": true}},
+			`select x`, []any{}, ""},
+		{`select {{:a x || y}}`, []any{map[string]any{"a": false}},
+			`select y`, []any{}, ""},
+		{`select {{:a (x || y) || z}}`, []any{map[string]any{"a": true}},
+			`select (x || y)`, []any{}, ""},
+		{`select {{:a x || y || z}}`, []any{map[string]any{"a": false}},
+			`select y || z`, []any{}, ""},
+		{`select {{:a x || {{:b y || z}}}}`, []any{map[string]any{"a": false, "b": false}},
+			`select z`, []any{}, ""},
+
+		// Literals and comments
+		{`select '{{:a x}}', "{{:a x}}" -- {{:a x}}`, []any{map[string]any{"a": false}},
+			`select '{{:a x}}', "{{:a x}}" -- {{:a x}}`, []any{}, ""},
+		{`select {{:a 'x}}' || '}}'}}`, []any{map[string]any{"a": true}},
+			`select 'x}}'`, []any{}, ""},
+		{`select {{:a 'x}}' || '}}'}}`, []any{map[string]any{"a": false}},
+			`select '}}'`, []any{}, ""},
+
+		// Whitespace
+		{"select x\nfrom t\nwhere\n\t{{:a x = 1}}\n\tand y = 2\n", []any{map[string]any{"a": false}},
+			"select x\nfrom t\nwhere\n\tand y = 2\n", []any{}, ""},
+		{"select x\nfrom t\n\t{{:a where x = 1}}\n", []any{map[string]any{"a": false}},
+			"select x\nfrom t\n", []any{}, ""},
+		{`insert into t (a {{:b , b}}) values (1)`, []any{map[string]any{"b": false}},
+			`insert into t (a) values (1)`, []any{}, ""},
+		{`select f( {{:a x}} )`, []any{map[string]any{"a": false}},
+			`select f()`, []any{}, ""},
+
+		// Positional
+		{`select x where {{:1 a = ?}} {{:2 and b = ?}}`, []any{1, 0},
+			`select x where a = $1`, []any{1}, ""},
+		{`select x where {{:1 a = ?}} {{:2 and b = ?}}`, []any{"", "y"},
+			`select x where and b = $1`, []any{"y"}, ""},
+		{`select x where {{:1 a = ? || a is null}}`, []any{(*int)(nil)},
+			`select x where a is null`, []any{}, ""},
+		{`select x where {{:1 a = ?}} and b = ?`, []any{1},
+			``, nil, "conditionals with positional parameters"},
+		{`select x where {{:3 a}}`, []any{1},
+			``, nil, "no parameter 3"},
 	}
 
 	for _, tt := range tests {
@@ -197,6 +257,60 @@ func TestPrepare(t *testing.T) {
 	}
 }
 
+func TestReplaceConditionalsTemplate(t *testing.T) {
+	tests := []struct {
+		query, want string
+	}{
+		{`select {%:a x%} {%:b y || z%}`, `select x z`},
+		{`select {{:a x}} {%:a x[y[1]] || '%}'%}`, `select {{:a x}} x[y[1]]`},
+		{"select 1\n\t{%:b x%}\nfrom t", "select 1\nfrom t"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			have, _, err := replaceConditionals(tt.query, map[string]any{"a": true, "b": false}, tplCondDelims)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != tt.want {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepareLint(t *testing.T) {
+	test.Use()
+	db, err := Connect(context.Background(), ConnectOptions{Connect: "test+", LintParams: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithDB(context.Background(), db)
+
+	tests := []struct {
+		query   string
+		args    []any
+		wantErr string
+	}{
+		{`select :a`, []any{map[string]any{"a": 1}}, ""},
+		{`select {{:a x}}`, []any{map[string]any{"a": 1}}, ""},
+		{`select {{:a x || y = :b}}`, []any{map[string]any{"a": 1, "b": 2}}, ""},
+		{`select :a`, []any{map[string]any{"a": 1}, struct{ B int }{2}}, ""},
+		{`select ':a'`, []any{map[string]any{"a": 1}}, `unused parameters: "a"`},
+		{`select :a`, []any{map[string]any{"a": 1, "c": 1, "b": 2}}, `unused parameters: "b", "c"`},
+		{`select ?`, []any{1}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			_, _, err := prepareImpl(ctx, MustGetDB(ctx), tt.query, tt.args...)
+			if !ztest.ErrorContains(err, tt.wantErr) {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
 func TestPrepareIn(t *testing.T) {
 	tests := []struct {
 		query  string
@@ -304,6 +418,52 @@ func TestSQLParameter(t *testing.T) {
 	}
 }
 
+func TestSQLParameterPositional(t *testing.T) {
+	tests := []struct {
+		query   string
+		params  []any
+		want    string
+		wantErr string
+	}{
+		{`select * from y where z ? (?)`, []any{SQL("in"), 2},
+			`select * from y where z in (?) []interface {}{2}`, ""},
+		{`select ? from y where z = ? order by ?`, []any{1, 2, SQL("x desc")},
+			`select ? from y where z = ? order by x desc []interface {}{1, 2}`, ""},
+		{`select ?, '?', "?" -- ?` + "\n" + `from ?`, []any{1, SQL("t")},
+			`select ?, '?', "?" -- ?` + "\n" + `from t []interface {}{1}`, ""},
+		{`select * from y where z = $2 and q = $3 order by $1`, []any{SQL("z"), 2, 3},
+			`select * from y where z = $1 and q = $2 order by z []interface {}{2, 3}`, ""},
+		{`select $2, $2, $1, $3`, []any{1, SQL("a"), 3},
+			`select a, a, $1, $2 []interface {}{1, 3}`, ""},
+		{`select '$1', $1, $2`, []any{SQL("a"), 3},
+			`select '$1', a, $1 []interface {}{3}`, ""},
+
+		{`select ?, $1`, []any{SQL("a")},
+			``, "can't mix"},
+		{`select ?`, []any{1, SQL("a")},
+			``, "SQL() parameter 2: no placeholder"},
+	}
+
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			ctx := testdriver(t)
+
+			query, params, err := prepareImpl(ctx, MustGetDB(ctx), tt.query, tt.params...)
+			if !ztest.ErrorContains(err, tt.wantErr) {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" {
+				return
+			}
+
+			have := fmt.Sprintf("%s %#v", query, params)
+			if have != tt.want {
+				t.Errorf("\nhave: %#v\nwant: %#v", have, tt.want)
+			}
+		})
+	}
+}
+
 func BenchmarkPrepare(b *testing.B) {
 	query := `
  		select foo from bar
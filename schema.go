@@ -0,0 +1,746 @@
+package zdb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"maps"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"zgo.at/zdb/internal/sqltoken"
+	"zgo.at/zstd/zfs"
+)
+
+type (
+	// Table is a table or view in the database.
+	Table struct {
+		Name string
+		View bool
+	}
+
+	// Column is a column in a table or view.
+	Column struct {
+		Name          string
+		Type          string // Type as reported by the database, in lower case.
+		Nullable      bool
+		Default       string // Default expression; empty if there is none.
+		PrimaryKey    bool   // Part of the primary key.
+		AutoIncrement bool   // serial, identity, auto_increment, or SQLite's "integer primary key".
+		Generated     bool   // Generated ("computed") column.
+	}
+
+	// Index is an index on a table.
+	Index struct {
+		Name    string
+		Columns []string // Column names or expressions, in index order.
+		Unique  bool
+		Primary bool
+	}
+
+	// ForeignKey is a foreign key constraint.
+	ForeignKey struct {
+		Name       string // Always empty on SQLite, as it doesn't name constraints.
+		Columns    []string
+		RefTable   string
+		RefColumns []string
+		OnUpdate   string // Action in upper case, e.g. "NO ACTION" or "CASCADE".
+		OnDelete   string
+	}
+
+	// Check is a check constraint.
+	Check struct {
+		Name string // Always empty on SQLite.
+		Expr string // Expression as reported by the database, without "check".
+	}
+)
+
+// Tables lists all tables and views in the current schema or database, ordered
+// by name.
+//
+// Internal SQLite tables (sqlite_*) are never included.
+func Tables(ctx context.Context) ([]Table, error) {
+	var (
+		tables []Table
+		err    error
+	)
+	switch SQLDialect(ctx) {
+	case DialectPostgreSQL:
+		err = Select(ctx, &tables, `
+			select
+				table_name                 as name,
+				table_type = 'VIEW'        as view
+			from information_schema.tables
+			where table_schema = current_schema()
+			order by table_name`)
+	case DialectSQLite:
+		err = Select(ctx, &tables, `
+			select
+				name,
+				type = 'view'              as view
+			from sqlite_schema
+			where type in ('table', 'view') and name not like 'sqlite_%'
+			order by name`)
+	case DialectMariaDB:
+		err = Select(ctx, &tables, `
+			select
+				table_name                 as name,
+				table_type = 'VIEW'        as view
+			from information_schema.tables
+			where table_schema = database()
+			order by table_name`)
+	default:
+		err = fmt.Errorf("unsupported dialect %s", SQLDialect(ctx))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("zdb.Tables: %w", err)
+	}
+	return tables, nil
+}
+
+// Columns lists all columns for a table or view, in the order they're defined.
+//
+// It's an error if the table doesn't exist.
+func Columns(ctx context.Context, table string) ([]Column, error) {
+	var (
+		cols []Column
+		err  error
+	)
+	switch SQLDialect(ctx) {
+	case DialectPostgreSQL:
+		err = Select(ctx, &cols, `
+			select
+				a.attname                                       as name,
+				format_type(a.atttypid, a.atttypmod)            as type,
+				not a.attnotnull                                as nullable,
+				case when a.attgenerated = '' then coalesce(pg_get_expr(d.adbin, d.adrelid), '') else '' end as "default",
+				coalesce(a.attnum = any(i.indkey), false)       as primarykey,
+				a.attidentity != '' or coalesce(pg_get_expr(d.adbin, d.adrelid), '') like 'nextval(%' as autoincrement,
+				a.attgenerated != ''                            as generated
+			from pg_attribute a
+			join pg_class c          on c.oid = a.attrelid
+			join pg_namespace n      on n.oid = c.relnamespace
+			left join pg_attrdef d   on d.adrelid = a.attrelid and d.adnum = a.attnum
+			left join pg_index i     on i.indrelid = a.attrelid and i.indisprimary
+			where n.nspname = current_schema() and c.relname = ? and a.attnum > 0 and not a.attisdropped
+			order by a.attnum`, table)
+	case DialectSQLite:
+		var rows []struct {
+			Name     string  `db:"name"`
+			Type     string  `db:"type"`
+			NotNull  bool    `db:"notnull"`
+			Default  *string `db:"dflt_value"`
+			PK       int     `db:"pk"`
+			Hidden   int     `db:"hidden"`
+			Autoincr bool    `db:"autoincr"`
+		}
+		err = Select(ctx, &rows, `
+			select
+				name, type, "notnull", dflt_value, pk, hidden,
+				(select count(*) from pragma_table_xinfo(:t) where pk > 0) = 1 and pk = 1 and lower(type) = 'integer' as autoincr
+			from pragma_table_xinfo(:t)
+			order by cid`, map[string]any{"t": table})
+		for _, r := range rows {
+			c := Column{
+				Name:          r.Name,
+				Type:          strings.ToLower(r.Type),
+				Nullable:      !r.NotNull && r.PK == 0,
+				PrimaryKey:    r.PK > 0,
+				AutoIncrement: r.Autoincr,
+				Generated:     r.Hidden == 2 || r.Hidden == 3,
+			}
+			if r.Default != nil && !c.Generated {
+				c.Default = *r.Default
+			}
+			cols = append(cols, c)
+		}
+	case DialectMariaDB:
+		err = Select(ctx, &cols, `
+			select
+				column_name                                     as name,
+				lower(column_type)                              as type,
+				is_nullable = 'YES'                             as nullable,
+				case when column_default is null or column_default = 'NULL' or is_generated = 'ALWAYS' then '' else column_default end as `+"`default`"+`,
+				column_key = 'PRI'                              as primarykey,
+				extra like '%auto_increment%'                   as autoincrement,
+				is_generated = 'ALWAYS'                         as generated
+			from information_schema.columns
+			where table_schema = database() and table_name = ?
+			order by ordinal_position`, table)
+	default:
+		err = fmt.Errorf("unsupported dialect %s", SQLDialect(ctx))
+	}
+	if err == nil && len(cols) == 0 {
+		err = fmt.Errorf("table %q doesn't exist", table)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("zdb.Columns: %w", err)
+	}
+	return cols, nil
+}
+
+// Indexes lists all indexes for a table, ordered by name.
+//
+// This includes the indexes for primary keys and unique constraints, except on
+// SQLite for an "integer primary key", which doesn't have an index.
+func Indexes(ctx context.Context, table string) ([]Index, error) {
+	var (
+		rows []struct {
+			Name    string `db:"name"`
+			Unique  bool   `db:"is_unique"`
+			Primary bool   `db:"is_primary"`
+			Column  string `db:"col"`
+		}
+		err error
+	)
+	switch SQLDialect(ctx) {
+	case DialectPostgreSQL:
+		err = Select(ctx, &rows, `
+			select
+				ic.relname                                      as name,
+				i.indisunique                                   as is_unique,
+				i.indisprimary                                  as is_primary,
+				pg_get_indexdef(i.indexrelid, k, true)          as col
+			from pg_index i
+			join pg_class ic         on ic.oid = i.indexrelid
+			join pg_class c          on c.oid = i.indrelid
+			join pg_namespace n      on n.oid = c.relnamespace
+			cross join generate_series(1, i.indnkeyatts) k
+			where n.nspname = current_schema() and c.relname = ?
+			order by ic.relname, k`, table)
+	case DialectSQLite:
+		err = Select(ctx, &rows, `
+			select
+				l.name                                          as name,
+				l."unique"                                      as is_unique,
+				l.origin = 'pk'                                 as is_primary,
+				coalesce(i.name, '')                            as col
+			from pragma_index_list(?) l
+			join pragma_index_info(l.name) i
+			order by l.name, i.seqno`, table)
+	case DialectMariaDB:
+		err = Select(ctx, &rows, `
+			select
+				index_name                                      as name,
+				non_unique = 0                                  as is_unique,
+				index_name = 'PRIMARY'                          as is_primary,
+				column_name                                     as col
+			from information_schema.statistics
+			where table_schema = database() and table_name = ?
+			order by index_name, seq_in_index`, table)
+	default:
+		err = fmt.Errorf("unsupported dialect %s", SQLDialect(ctx))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("zdb.Indexes: %w", err)
+	}
+
+	var idx []Index
+	for _, r := range rows {
+		if len(idx) == 0 || idx[len(idx)-1].Name != r.Name {
+			idx = append(idx, Index{Name: r.Name, Unique: r.Unique, Primary: r.Primary})
+		}
+		idx[len(idx)-1].Columns = append(idx[len(idx)-1].Columns, r.Column)
+	}
+	return idx, nil
+}
+
+// ForeignKeys lists all foreign keys for a table, ordered by name (or by the
+// order they were defined in on SQLite).
+func ForeignKeys(ctx context.Context, table string) ([]ForeignKey, error) {
+	var (
+		rows []struct {
+			ID        string `db:"id"`
+			Name      string `db:"name"`
+			Column    string `db:"col"`
+			RefTable  string `db:"ref_table"`
+			RefColumn string `db:"ref_col"`
+			OnUpdate  string `db:"on_update"`
+			OnDelete  string `db:"on_delete"`
+		}
+		err error
+	)
+	switch SQLDialect(ctx) {
+	case DialectPostgreSQL:
+		err = Select(ctx, &rows, `
+			select
+				con.conname                                     as id,
+				con.conname                                     as name,
+				a.attname                                       as col,
+				rc.relname                                      as ref_table,
+				ra.attname                                      as ref_col,
+				case con.confupdtype when 'r' then 'RESTRICT' when 'c' then 'CASCADE' when 'n' then 'SET NULL'
+					when 'd' then 'SET DEFAULT' else 'NO ACTION' end as on_update,
+				case con.confdeltype when 'r' then 'RESTRICT' when 'c' then 'CASCADE' when 'n' then 'SET NULL'
+					when 'd' then 'SET DEFAULT' else 'NO ACTION' end as on_delete
+			from pg_constraint con
+			join pg_class c          on c.oid = con.conrelid
+			join pg_namespace n      on n.oid = c.relnamespace
+			join pg_class rc         on rc.oid = con.confrelid
+			cross join unnest(con.conkey, con.confkey) with ordinality as k(col, ref_col, pos)
+			join pg_attribute a      on a.attrelid = con.conrelid  and a.attnum = k.col
+			join pg_attribute ra     on ra.attrelid = con.confrelid and ra.attnum = k.ref_col
+			where con.contype = 'f' and n.nspname = current_schema() and c.relname = ?
+			order by con.conname, k.pos`, table)
+	case DialectSQLite:
+		// "to" is null if the foreign key references the primary key.
+		err = Select(ctx, &rows, `
+			select
+				cast(f.id as text)                              as id,
+				''                                              as name,
+				f."from"                                        as col,
+				f."table"                                       as ref_table,
+				coalesce(f."to", (select name from pragma_table_info(f."table") where pk = f.seq + 1), '') as ref_col,
+				upper(f.on_update)                              as on_update,
+				upper(f.on_delete)                              as on_delete
+			from pragma_foreign_key_list(?) f
+			order by f.id desc, f.seq`, table)
+	case DialectMariaDB:
+		err = Select(ctx, &rows, `
+			select
+				k.constraint_name                               as id,
+				k.constraint_name                               as name,
+				k.column_name                                   as col,
+				k.referenced_table_name                         as ref_table,
+				k.referenced_column_name                        as ref_col,
+				upper(r.update_rule)                            as on_update,
+				upper(r.delete_rule)                            as on_delete
+			from information_schema.key_column_usage k
+			join information_schema.referential_constraints r
+				on r.constraint_schema = k.constraint_schema and r.constraint_name = k.constraint_name
+			where k.table_schema = database() and k.table_name = ? and k.referenced_table_name is not null
+			order by k.constraint_name, k.ordinal_position`, table)
+	default:
+		err = fmt.Errorf("unsupported dialect %s", SQLDialect(ctx))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("zdb.ForeignKeys: %w", err)
+	}
+
+	var (
+		fks  []ForeignKey
+		last string
+	)
+	for _, r := range rows {
+		if len(fks) == 0 || last != r.ID {
+			fks = append(fks, ForeignKey{Name: r.Name, RefTable: r.RefTable, OnUpdate: r.OnUpdate, OnDelete: r.OnDelete})
+			last = r.ID
+		}
+		fk := &fks[len(fks)-1]
+		fk.Columns, fk.RefColumns = append(fk.Columns, r.Column), append(fk.RefColumns, r.RefColumn)
+	}
+	return fks, nil
+}
+
+// Checks lists all check constraints for a table, ordered by name (or by the
+// order they were defined in on SQLite).
+//
+// SQLite doesn't store check constraints other than in the "create table"
+// statement, so they're read from that.
+func Checks(ctx context.Context, table string) ([]Check, error) {
+	var (
+		checks []Check
+		err    error
+	)
+	switch SQLDialect(ctx) {
+	case DialectPostgreSQL:
+		err = Select(ctx, &checks, `
+			select
+				con.conname                                     as name,
+				pg_get_expr(con.conbin, con.conrelid)           as expr
+			from pg_constraint con
+			join pg_class c          on c.oid = con.conrelid
+			join pg_namespace n      on n.oid = c.relnamespace
+			where con.contype = 'c' and n.nspname = current_schema() and c.relname = ?
+			order by con.conname`, table)
+	case DialectSQLite:
+		var sql string
+		err = Get(ctx, &sql, `select sql from sqlite_schema where type = 'table' and name = ?`, table)
+		checks = sqliteChecks(sql)
+	case DialectMariaDB:
+		err = Select(ctx, &checks, `
+			select
+				constraint_name                                 as name,
+				check_clause                                    as expr
+			from information_schema.check_constraints
+			where constraint_schema = database() and table_name = ?
+			order by constraint_name`, table)
+	default:
+		err = fmt.Errorf("unsupported dialect %s", SQLDialect(ctx))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("zdb.Checks: %w", err)
+	}
+	return checks, nil
+}
+
+// sqliteChecks gets the expressions of all "check (..)" clauses in a SQLite
+// "create table" statement. Comments are removed and whitespace is collapsed
+// to a single space.
+func sqliteChecks(sql string) []Check {
+	var (
+		checks  []Check
+		expr    *strings.Builder
+		depth   int
+		isCheck bool // Seen "check", but not the opening paren yet.
+	)
+	for _, t := range sqltoken.Tokenize(sql, tokenConfig(DialectSQLite)) {
+		if expr == nil {
+			switch {
+			case t.Type == sqltoken.Word && strings.EqualFold(t.Text, "check"):
+				isCheck = true
+			case isCheck && (t.Type == sqltoken.Whitespace || t.Type == sqltoken.Comment):
+			case isCheck && t.Type == sqltoken.Punctuation && t.Text[0] == '(':
+				// Punctuation is combined, so this can be e.g. "((".
+				expr, depth, isCheck = new(strings.Builder), 1, false
+				t.Text = t.Text[1:]
+			default:
+				isCheck = false
+			}
+			if expr == nil {
+				continue
+			}
+		}
+
+		switch t.Type {
+		case sqltoken.Comment, sqltoken.Whitespace:
+			if !strings.HasSuffix(expr.String(), " ") {
+				expr.WriteByte(' ')
+			}
+		case sqltoken.Punctuation:
+			for _, c := range t.Text {
+				switch c {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				}
+				if depth == 0 {
+					checks = append(checks, Check{Expr: strings.TrimSpace(expr.String())})
+					expr = nil
+					break
+				}
+				expr.WriteRune(c)
+			}
+		default:
+			expr.WriteString(t.Text)
+		}
+	}
+	return checks
+}
+
+// SchemaDiffOptions are options for [SchemaDiff].
+type SchemaDiffOptions struct {
+	// Database files and Go migrations; see [ConnectOptions].
+	Files        fs.FS
+	GoMigrations map[string]func(context.Context) error
+}
+
+// SchemaDiff reports the differences between a database created from the
+// schema file and a database with all migrations run on an empty schema.
+//
+// This is useful to verify that schema.sql (which is used for new databases)
+// and the migrations haven't drifted apart. It returns one line for every
+// difference in tables, columns (type, nullability, default, primary key,
+// generated), indexes, foreign keys, and check constraints, or nil if there
+// are no differences. The order of columns is ignored.
+//
+// Both databases are temporary and removed afterwards: on PostgreSQL it creates
+// a new schema and on MariaDB a new database with the connection from ctx,
+// which needs permission to do so. SQLite always uses a new in-memory database.
+//
+// See [TestSchemaDiff] for a test helper.
+func SchemaDiff(ctx context.Context, opt SchemaDiffOptions) ([]string, error) {
+	files, err := zfs.SubIfExists(opt.Files, "db")
+	if err != nil {
+		return nil, fmt.Errorf("zdb.SchemaDiff: %w", err)
+	}
+
+	db := MustGetDB(ctx)
+	createDB, dropCreate, err := scratchDB(ctx, db, "create")
+	if err != nil {
+		return nil, fmt.Errorf("zdb.SchemaDiff: %w", err)
+	}
+	defer dropCreate()
+	migrateDB, dropMigrate, err := scratchDB(ctx, db, "migrate")
+	if err != nil {
+		return nil, fmt.Errorf("zdb.SchemaDiff: %w", err)
+	}
+	defer dropMigrate()
+
+	// Same as Connect() does for new databases: create from the schema and run
+	// any migrations not recorded in it.
+	err = Create(createDB, files)
+	if err != nil {
+		return nil, fmt.Errorf("zdb.SchemaDiff: %w", err)
+	}
+	if zfs.Exists(files, "migrate") {
+		m, err := NewMigrate(createDB, files, opt.GoMigrations)
+		if err != nil {
+			return nil, fmt.Errorf("zdb.SchemaDiff: %w", err)
+		}
+		err = m.Run("all")
+		if err != nil {
+			return nil, fmt.Errorf("zdb.SchemaDiff: %w", err)
+		}
+	}
+
+	diff, err := migrateAndDiff(WithDB(ctx, createDB), WithDB(ctx, migrateDB), files, opt.GoMigrations)
+	if err != nil {
+		return nil, fmt.Errorf("zdb.SchemaDiff: %w", err)
+	}
+	return diff, nil
+}
+
+// scratchDB creates a new empty database for db's dialect, and returns a
+// function to remove it again.
+//
+// PostgreSQL and MariaDB use a new schema or database on a dedicated
+// connection, so the search_path or current database of db isn't changed.
+func scratchDB(ctx context.Context, db DB, name string) (DB, func() error, error) {
+	if db.SQLDialect() == DialectSQLite {
+		// Every new connection to an in-memory database gets a new empty
+		// database, so limit it to one.
+		d, err := Connect(ctx, ConnectOptions{Connect: "sqlite+:memory:", MaxOpenConns: 1, MaxIdleConns: 1})
+		if err != nil {
+			return nil, nil, err
+		}
+		return d, d.Close, nil
+	}
+
+	var parent *zDB
+	switch d := Unwrap(db).(type) {
+	case *zDB:
+		parent = d
+	case *zTX:
+		parent = d.parent
+	case *zConn:
+		parent = d.parent
+	default:
+		return nil, nil, fmt.Errorf("unknown DB type %T", db)
+	}
+
+	name = fmt.Sprintf("zdb_schemadiff_%s_%x", name, rand.Uint32())
+	var create, use, drop string
+	switch db.SQLDialect() {
+	case DialectPostgreSQL:
+		create, use, drop = `create schema `+name, `set search_path to `+name, `drop schema `+name+` cascade`
+	case DialectMariaDB:
+		create, use, drop = `create database `+name, `use `+name, `drop database `+name
+	default:
+		return nil, nil, fmt.Errorf("unsupported dialect %s", db.SQLDialect())
+	}
+
+	conn, err := connImpl(ctx, parent)
+	if err != nil {
+		return nil, nil, err
+	}
+	err = conn.Exec(ctx, create)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	dropFn := func() error {
+		defer conn.Close()
+		return conn.Exec(context.Background(), drop)
+	}
+	err = conn.Exec(ctx, use)
+	if err != nil {
+		dropFn()
+		return nil, nil, err
+	}
+	return conn, dropFn, nil
+}
+
+// migrateAndDiff runs all migrations on migrateCtx and compares it to
+// createCtx.
+func migrateAndDiff(createCtx, migrateCtx context.Context, files fs.FS, gomig map[string]func(context.Context) error) ([]string, error) {
+	files, err := zfs.SubIfExists(files, "db")
+	if err != nil {
+		return nil, err
+	}
+	if zfs.Exists(files, "migrate") {
+		m, err := NewMigrate(MustGetDB(migrateCtx), files, gomig)
+		if err != nil {
+			return nil, err
+		}
+		err = m.Run("all")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return diffSchemas(createCtx, migrateCtx, "schema", "migrations")
+}
+
+// schemaInfo is everything we know about a schema, keyed by table name.
+type schemaInfo map[string]struct {
+	table   Table
+	cols    []Column
+	indexes []Index
+	fks     []ForeignKey
+	checks  []Check
+}
+
+func loadSchema(ctx context.Context) (schemaInfo, error) {
+	tables, err := Tables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s := make(schemaInfo)
+	for _, t := range tables {
+		info := s[t.Name]
+		info.table = t
+		info.cols, err = Columns(ctx, t.Name)
+		if err != nil {
+			return nil, err
+		}
+		if !t.View {
+			info.indexes, err = Indexes(ctx, t.Name)
+			if err != nil {
+				return nil, err
+			}
+			info.fks, err = ForeignKeys(ctx, t.Name)
+			if err != nil {
+				return nil, err
+			}
+			info.checks, err = Checks(ctx, t.Name)
+			if err != nil {
+				return nil, err
+			}
+		}
+		s[t.Name] = info
+	}
+	return s, nil
+}
+
+// diffSchemas compares the schemas of two databases; the names are used in the
+// output to refer to a and b.
+func diffSchemas(a, b context.Context, nameA, nameB string) ([]string, error) {
+	sa, err := loadSchema(a)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := loadSchema(b)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		diff  []string
+		names = slices.Sorted(maps.Keys(sa))
+	)
+	for n := range sb {
+		if _, ok := sa[n]; !ok {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+
+	add := func(format string, args ...any) { diff = append(diff, fmt.Sprintf(format, args...)) }
+	differ := func(prefix, what string, va, vb any) {
+		if !reflect.DeepEqual(va, vb) {
+			add("%s: %s %s in %s, %s in %s", prefix, what, fmtDiff(va), nameA, fmtDiff(vb), nameB)
+		}
+	}
+	for _, tbl := range names {
+		ta, okA := sa[tbl]
+		tb, okB := sb[tbl]
+		prefix := fmt.Sprintf("table %q", tbl)
+		if !okA || !okB {
+			add("%s: only in %s", prefix, map[bool]string{true: nameA, false: nameB}[okA])
+			continue
+		}
+		differ(prefix, "view", ta.table.View, tb.table.View)
+
+		// Columns
+		for _, ca := range ta.cols {
+			p := fmt.Sprintf("%s: column %q", prefix, ca.Name)
+			i := slices.IndexFunc(tb.cols, func(c Column) bool { return c.Name == ca.Name })
+			if i == -1 {
+				add("%s: only in %s", p, nameA)
+				continue
+			}
+			cb := tb.cols[i]
+			differ(p, "type", ca.Type, cb.Type)
+			differ(p, "nullable", ca.Nullable, cb.Nullable)
+			differ(p, "default", ca.Default, cb.Default)
+			differ(p, "primary key", ca.PrimaryKey, cb.PrimaryKey)
+			differ(p, "auto increment", ca.AutoIncrement, cb.AutoIncrement)
+			differ(p, "generated", ca.Generated, cb.Generated)
+		}
+		for _, cb := range tb.cols {
+			if !slices.ContainsFunc(ta.cols, func(c Column) bool { return c.Name == cb.Name }) {
+				add("%s: column %q: only in %s", prefix, cb.Name, nameB)
+			}
+		}
+
+		// Indexes
+		for _, ia := range ta.indexes {
+			p := fmt.Sprintf("%s: index %q", prefix, ia.Name)
+			i := slices.IndexFunc(tb.indexes, func(x Index) bool { return x.Name == ia.Name })
+			if i == -1 {
+				add("%s: only in %s", p, nameA)
+				continue
+			}
+			ib := tb.indexes[i]
+			differ(p, "columns", ia.Columns, ib.Columns)
+			differ(p, "unique", ia.Unique, ib.Unique)
+			differ(p, "primary", ia.Primary, ib.Primary)
+		}
+		for _, ib := range tb.indexes {
+			if !slices.ContainsFunc(ta.indexes, func(x Index) bool { return x.Name == ib.Name }) {
+				add("%s: index %q: only in %s", prefix, ib.Name, nameB)
+			}
+		}
+
+		// Foreign keys; compare by the columns, as the names are often
+		// generated.
+		fkName := func(fk ForeignKey) string {
+			return fmt.Sprintf("(%s) → %s(%s)", strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "))
+		}
+		for _, fa := range ta.fks {
+			p := fmt.Sprintf("%s: foreign key %s", prefix, fkName(fa))
+			i := slices.IndexFunc(tb.fks, func(f ForeignKey) bool { return fkName(f) == fkName(fa) })
+			if i == -1 {
+				add("%s: only in %s", p, nameA)
+				continue
+			}
+			fb := tb.fks[i]
+			differ(p, "on update", fa.OnUpdate, fb.OnUpdate)
+			differ(p, "on delete", fa.OnDelete, fb.OnDelete)
+		}
+		for _, fb := range tb.fks {
+			if !slices.ContainsFunc(ta.fks, func(f ForeignKey) bool { return fkName(f) == fkName(fb) }) {
+				add("%s: foreign key %s: only in %s", prefix, fkName(fb), nameB)
+			}
+		}
+
+		// Check constraints; compare by the expression, as the names are often
+		// generated.
+		for _, ca := range ta.checks {
+			if !slices.ContainsFunc(tb.checks, func(c Check) bool { return c.Expr == ca.Expr }) {
+				add("%s: check %q: only in %s", prefix, ca.Expr, nameA)
+			}
+		}
+		for _, cb := range tb.checks {
+			if !slices.ContainsFunc(ta.checks, func(c Check) bool { return c.Expr == cb.Expr }) {
+				add("%s: check %q: only in %s", prefix, cb.Expr, nameB)
+			}
+		}
+	}
+	return diff, nil
+}
+
+func fmtDiff(v any) string {
+	switch vv := v.(type) {
+	case string:
+		return strconv.Quote(vv)
+	case []string:
+		return "(" + strings.Join(vv, ", ") + ")"
+	default:
+		return fmt.Sprint(vv)
+	}
+}
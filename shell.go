@@ -0,0 +1,492 @@
+package zdb
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"zgo.at/zdb/internal/sqltoken"
+	"zgo.at/zstd/ztime"
+)
+
+// ShellOptions are options for [Shell].
+type ShellOptions struct {
+	In  io.Reader // Read commands from; default is stdin.
+	Out io.Writer // Write output to; default is stdout.
+
+	// Prompt to show; a continuation prompt of the same width is used for
+	// multi-line statements. No prompt is shown if this is empty, which is
+	// useful when reading from a file or pipe.
+	Prompt string
+
+	// File to load the history from, and save new history to. History is not
+	// saved if this is empty.
+	History string
+}
+
+// Maximum length of a line.
+const shellMaxLine = 16 * 1024 * 1024
+
+// Newlines in multi-line statements are stored as this in the history file, so
+// that every entry is one line; this is the same as psql.
+const shellHistNL = "\x01"
+
+const shellHelp = `Statements are run when they end with a ";", and may span several lines.
+
+Meta-commands:
+  \d [table]       List tables, or describe a table.
+  \x               Toggle vertical output.
+  \format [fmt]    Set output format: table, csv, json, or html.
+  \timing          Toggle showing how long queries take.
+  \explain         Toggle showing the query plan; note this runs "explain
+                   analyze" on PostgreSQL, which runs the query twice.
+  \s               Show history.
+  \?               Show this help.
+  \q               Quit.
+
+Queries from the query directory can be run with load:name, with named
+parameters as name=value; strings with spaces must be quoted:
+
+  load:select-sites site=1 name='a b';
+`
+
+type shell struct {
+	ctx     context.Context
+	out     io.Writer
+	dump    DumpArg
+	timing  bool
+	history []string
+	histOut io.Writer
+}
+
+// Shell runs an interactive SQL shell.
+//
+// Statements end with a ";", and may span several lines; a ";" inside a
+// begin .. end block, such as in a "create trigger", doesn't end the statement.
+// Output is formatted with [Dump]. Queries can be loaded from the query
+// directory with "load:name", with named parameters as name=value.
+//
+// There are some backslash meta-commands, similar to psql:
+//
+//	\d [table]       List tables, or describe a table.
+//	\x               Toggle vertical output.
+//	\format [fmt]    Set output format: table, csv, json, or html.
+//	\timing          Toggle showing how long queries take.
+//	\explain         Toggle showing the query plan.
+//	\s               Show history.
+//	\?               Show help.
+//	\q               Quit.
+//
+// All statements are run on the same connection, so that session state such as
+// transactions started with "begin" or "set search_path" is kept between
+// statements. Any open transaction is rolled back when the shell exits.
+//
+// There is no line editing; use a tool like rlwrap for that.
+func Shell(ctx context.Context, opt ShellOptions) error {
+	if opt.In == nil {
+		opt.In = os.Stdin
+	}
+	if opt.Out == nil {
+		opt.Out = os.Stdout
+	}
+
+	db := MustGetDB(ctx)
+	conn, err := connImpl(ctx, db)
+	if err != nil {
+		return fmt.Errorf("zdb.Shell: %w", err)
+	}
+	if conn != db {
+		defer func() {
+			// Errors if there is no transaction; that's okay.
+			conn.Exec(context.Background(), `rollback`)
+			conn.Close()
+		}()
+	}
+	ctx = WithDB(ctx, conn)
+
+	sh := shell{ctx: ctx, out: opt.Out, dump: DumpResult}
+	if opt.History != "" {
+		h, err := os.ReadFile(opt.History)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("zdb.Shell: %w", err)
+		}
+		if len(h) > 0 {
+			sh.history = strings.Split(strings.TrimRight(string(h), "\n"), "\n")
+			for i := range sh.history {
+				sh.history[i] = strings.ReplaceAll(sh.history[i], shellHistNL, "\n")
+			}
+		}
+		fp, err := os.OpenFile(opt.History, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return fmt.Errorf("zdb.Shell: %w", err)
+		}
+		defer fp.Close()
+		sh.histOut = fp
+	}
+
+	var (
+		prompt2 = strings.Repeat(" ", max(len(opt.Prompt)-3, 0)) + "-> "
+		buf     strings.Builder
+		scan    = bufio.NewScanner(opt.In)
+		config  = tokenConfig(SQLDialect(ctx))
+	)
+	scan.Buffer(make([]byte, 0, 64*1024), shellMaxLine)
+	if opt.Prompt == "" {
+		prompt2 = ""
+	}
+	fmt.Fprint(sh.out, opt.Prompt)
+	for scan.Scan() {
+		line := scan.Text()
+
+		// Meta-command; only at the start of a statement.
+		if buf.Len() == 0 && strings.HasPrefix(strings.TrimSpace(line), `\`) {
+			quit := sh.meta(strings.TrimSpace(line))
+			if quit {
+				return nil
+			}
+			fmt.Fprint(sh.out, opt.Prompt)
+			continue
+		}
+
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.WriteString(line)
+
+		// Run all complete statements; keep the rest in the buffer.
+		tokens := sqltoken.Tokenize(buf.String(), config)
+		ends := statementEnds(tokens)
+		if len(ends) == 0 {
+			if strings.TrimSpace(buf.String()) == "" {
+				buf.Reset()
+				fmt.Fprint(sh.out, opt.Prompt)
+			} else {
+				fmt.Fprint(sh.out, prompt2)
+			}
+			continue
+		}
+		start := 0
+		for _, i := range ends {
+			if stmt := strings.TrimSpace(sqltoken.Tokens(tokens[start:i]).String()); stmt != "" {
+				sh.run(stmt)
+			}
+			start = i + 1
+		}
+		rest := strings.TrimSpace(sqltoken.Tokens(tokens[start:]).String())
+		buf.Reset()
+		buf.WriteString(rest)
+		if rest == "" {
+			fmt.Fprint(sh.out, opt.Prompt)
+		} else {
+			fmt.Fprint(sh.out, prompt2)
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return fmt.Errorf("zdb.Shell: %w", err)
+	}
+
+	// Run anything left without a ";" at the end of the input.
+	if s := strings.TrimSpace(buf.String()); s != "" {
+		sh.run(s)
+	}
+	if opt.Prompt != "" {
+		fmt.Fprintln(sh.out)
+	}
+	return nil
+}
+
+// statementEnds gets the indexes of the semicolons that end a statement.
+//
+// Semicolons inside a begin .. end block don't end the statement, so that
+// "create trigger" on SQLite and MariaDB and compound statements on MariaDB
+// (and "begin atomic" on PostgreSQL) work. A "begin" at the start of a
+// statement starts a transaction, except for MariaDB's "begin not atomic".
+// Since case .. end can appear inside such a block, "case" also starts a block,
+// and MariaDB's "end if", "end loop", etc. don't end one.
+func statementEnds(tokens sqltoken.Tokens) []int {
+	var (
+		ends  []int
+		depth int
+		first = true // First word of the statement.
+		prev  string // Previous word, in lower case.
+	)
+	next := func(i int) string {
+		for _, t := range tokens[i+1:] {
+			if t.Type != sqltoken.Whitespace && t.Type != sqltoken.Comment {
+				return strings.ToLower(t.Text)
+			}
+		}
+		return ""
+	}
+	for i, t := range tokens {
+		switch t.Type {
+		case sqltoken.Semicolon:
+			if depth == 0 {
+				ends, first = append(ends, i), true
+			}
+			prev = ""
+		case sqltoken.Word:
+			w := strings.ToLower(t.Text)
+			switch {
+			case w == "begin" && (!first || next(i) == "not"):
+				depth++
+			case w == "case" && prev != "end":
+				depth++
+			case w == "end" && depth > 0:
+				switch next(i) {
+				case "if", "loop", "while", "repeat", "for":
+				default:
+					depth--
+				}
+			}
+			first, prev = false, w
+		case sqltoken.Whitespace, sqltoken.Comment:
+		default:
+			first, prev = false, ""
+		}
+	}
+	return ends
+}
+
+func tokenConfig(dialect Dialect) sqltoken.Config {
+	switch dialect {
+	case DialectMariaDB:
+		return sqltoken.MySQLConfig()
+	case DialectSQLite:
+		return sqltoken.SQLiteConfig()
+	default:
+		return sqltoken.PostgreSQLConfig()
+	}
+}
+
+func (sh *shell) addHistory(s string) {
+	sh.history = append(sh.history, s)
+	if sh.histOut != nil {
+		fmt.Fprintln(sh.histOut, strings.ReplaceAll(s, "\n", shellHistNL))
+	}
+}
+
+func (sh *shell) run(stmt string) {
+	sh.addHistory(stmt + ";")
+
+	var params []any
+	if strings.HasPrefix(stmt, "load:") {
+		name, args, _ := strings.Cut(stmt, " ")
+		p, err := shellParams(args)
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %s\n", err)
+			return
+		}
+		stmt = name
+		if len(p) > 0 {
+			params = append(params, p)
+		}
+	}
+
+	took := ztime.Takes(func() { Dump(sh.ctx, sh.out, stmt, append(params, sh.dump)...) })
+	if sh.timing {
+		fmt.Fprintf(sh.out, "Time: %s ms\n\n", ztime.DurationAs(took.Round(time.Microsecond), time.Millisecond))
+	}
+}
+
+// shellParams parses name=value parameters; values can be quoted with single
+// quotes.
+func shellParams(s string) (map[string]any, error) {
+	params := make(map[string]any)
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		k, v, ok := strings.Cut(s, "=")
+		if !ok || k == "" || strings.ContainsAny(k, " \t") {
+			return nil, fmt.Errorf("invalid parameter: %q", s)
+		}
+
+		end := strings.IndexAny(v, " \t")
+		if strings.HasPrefix(v, "'") {
+			end = -1
+			for i := 1; i < len(v); i++ {
+				if v[i] == '\'' {
+					if i+1 < len(v) && v[i+1] == '\'' {
+						i++
+						continue
+					}
+					end = i + 1
+					break
+				}
+			}
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated string for parameter %q", k)
+			}
+		}
+		if end == -1 {
+			end = len(v)
+		}
+		params[k] = shellParam(v[:end])
+		s = v[end:]
+	}
+	return params, nil
+}
+
+// shellParam converts a parameter value: a quoted string is unquoted, "null"
+// is NULL, and integers are an int64.
+func shellParam(v string) any {
+	if len(v) >= 2 && v[0] == '\'' && v[len(v)-1] == '\'' {
+		return strings.ReplaceAll(v[1:len(v)-1], "''", "'")
+	}
+	if strings.EqualFold(v, "null") {
+		return nil
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return n
+	}
+	return v
+}
+
+// meta runs a meta-command; it returns true if the shell should quit.
+func (sh *shell) meta(line string) bool {
+	sh.addHistory(line)
+
+	cmd, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch cmd {
+	default:
+		fmt.Fprintf(sh.out, "unknown command: %s; use \\? for help\n", cmd)
+	case `\q`:
+		return true
+	case `\?`:
+		fmt.Fprint(sh.out, shellHelp)
+	case `\s`:
+		for i, h := range sh.history {
+			fmt.Fprintf(sh.out, "%4d  %s\n", i+1, strings.ReplaceAll(h, "\n", "\n      "))
+		}
+	case `\x`:
+		sh.dump ^= DumpVertical
+		fmt.Fprintf(sh.out, "Vertical output is %s.\n", onOff(sh.dump.has(DumpVertical)))
+	case `\timing`:
+		sh.timing = !sh.timing
+		fmt.Fprintf(sh.out, "Timing is %s.\n", onOff(sh.timing))
+	case `\explain`:
+		sh.dump ^= DumpExplain
+		fmt.Fprintf(sh.out, "Explain is %s.\n", onOff(sh.dump.has(DumpExplain)))
+	case `\format`:
+		formats := map[string]DumpArg{"table": 0, "csv": DumpCSV, "json": DumpJSON, "html": DumpHTML}
+		f, ok := formats[arg]
+		if !ok {
+			fmt.Fprintf(sh.out, "unknown format %q; must be table, csv, json, or html\n", arg)
+			break
+		}
+		sh.dump = sh.dump&^(DumpCSV|DumpJSON|DumpHTML) | f
+		fmt.Fprintf(sh.out, "Output format is %s.\n", arg)
+	case `\d`:
+		var err error
+		if arg == "" {
+			err = sh.listTables()
+		} else {
+			err = sh.describe(arg)
+		}
+		if err != nil {
+			fmt.Fprintf(sh.out, "error: %s\n", err)
+		}
+	}
+	return false
+}
+
+func onOff(b bool) string {
+	if b {
+		return "on"
+	}
+	return "off"
+}
+
+func (sh *shell) listTables() error {
+	tables, err := Tables(sh.ctx)
+	if err != nil {
+		return err
+	}
+	t := tabwriter.NewWriter(sh.out, 4, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "name\ttype")
+	for _, tbl := range tables {
+		typ := "table"
+		if tbl.View {
+			typ = "view"
+		}
+		fmt.Fprintf(t, "%s\t%s\n", tbl.Name, typ)
+	}
+	t.Flush()
+	fmt.Fprintln(sh.out)
+	return nil
+}
+
+func (sh *shell) describe(table string) error {
+	cols, err := Columns(sh.ctx, table)
+	if err != nil {
+		return err
+	}
+	indexes, err := Indexes(sh.ctx, table)
+	if err != nil {
+		return err
+	}
+	fks, err := ForeignKeys(sh.ctx, table)
+	if err != nil {
+		return err
+	}
+
+	buf := new(bytes.Buffer)
+	t := tabwriter.NewWriter(buf, 4, 4, 2, ' ', 0)
+	fmt.Fprintln(t, "column\ttype\tnull\tdefault\t")
+	for _, c := range cols {
+		var (
+			null  = "not null"
+			extra []string
+		)
+		if c.Nullable {
+			null = "null"
+		}
+		if c.PrimaryKey {
+			extra = append(extra, "primary key")
+		}
+		if c.AutoIncrement {
+			extra = append(extra, "auto increment")
+		}
+		if c.Generated {
+			extra = append(extra, "generated")
+		}
+		fmt.Fprintf(t, "%s\t%s\t%s\t%s\t%s\n", c.Name, c.Type, null, c.Default, strings.Join(extra, ", "))
+	}
+	t.Flush()
+	sh.out.Write(reTrailingSpace.ReplaceAll(buf.Bytes(), []byte("\n")))
+
+	if len(indexes) > 0 {
+		fmt.Fprintln(sh.out, "\nIndexes:")
+		for _, i := range indexes {
+			var kind string
+			switch {
+			case i.Primary:
+				kind = " primary key"
+			case i.Unique:
+				kind = " unique"
+			}
+			fmt.Fprintf(sh.out, "  %s%s (%s)\n", i.Name, kind, strings.Join(i.Columns, ", "))
+		}
+	}
+	if len(fks) > 0 {
+		fmt.Fprintln(sh.out, "\nForeign keys:")
+		for _, fk := range fks {
+			name := fk.Name
+			if name != "" {
+				name += " "
+			}
+			fmt.Fprintf(sh.out, "  %s(%s) references %s(%s) on update %s on delete %s\n",
+				name, strings.Join(fk.Columns, ", "), fk.RefTable, strings.Join(fk.RefColumns, ", "),
+				strings.ToLower(fk.OnUpdate), strings.ToLower(fk.OnDelete))
+		}
+	}
+	fmt.Fprintln(sh.out)
+	return nil
+}
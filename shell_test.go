@@ -0,0 +1,45 @@
+package zdb
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"zgo.at/zdb/internal/sqltoken"
+)
+
+func TestStatementEnds(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{`select 1; select 2;`, []string{"select 1", "select 2"}},
+		{`begin; select 1; commit;`, []string{"begin", "select 1", "commit"}},
+		{`begin transaction; end;`, []string{"begin transaction", "end"}},
+		{`select case when 1 then 2 end; select 3`, []string{"select case when 1 then 2 end"}},
+		{`create trigger x after insert on t for each row begin insert into l values (1); end; select 1;`,
+			[]string{"create trigger x after insert on t for each row begin insert into l values (1); end", "select 1"}},
+		{`create trigger x after insert on t for each row begin select 1;`, nil},
+
+		// MariaDB
+		{`begin not atomic if 1 then select 1; end if; loop leave; end loop; end; select 2;`,
+			[]string{"begin not atomic if 1 then select 1; end if; loop leave; end loop; end", "select 2"}},
+		{`create procedure p() begin case when 1 then select 1; end case; select 2; end; select 3;`,
+			[]string{"create procedure p() begin case when 1 then select 1; end case; select 2; end", "select 3"}},
+	}
+	for _, tt := range tests {
+		t.Run("", func(t *testing.T) {
+			tokens := sqltoken.Tokenize(tt.in, tokenConfig(DialectMariaDB))
+			var (
+				have  []string
+				start int
+			)
+			for _, i := range statementEnds(tokens) {
+				have, start = append(have, strings.TrimSpace(tokens[start:i].String())), i+1
+			}
+			if !reflect.DeepEqual(have, tt.want) {
+				t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+			}
+		})
+	}
+}
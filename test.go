@@ -39,16 +39,7 @@ func RunTest(t *testing.T, f func(*testing.T, context.Context), opts ...drivers.
 		opt = &opts[0]
 	}
 
-	d := drivers.Drivers()
-	if TestDrivers != nil {
-		var newd []drivers.Driver
-		for _, dd := range d {
-			if slices.Contains(TestDrivers, dd.Name()) {
-				newd = append(newd, dd)
-			}
-		}
-		d = newd
-	}
+	d := testDrivers()
 	switch len(d) {
 	case 0:
 		t.Fatal("zdb.RunTest: no registered zdb drivers; you need to import a driver in your test")
@@ -75,16 +66,7 @@ func RunBench(b *testing.B, f func(*testing.B, context.Context), opts ...drivers
 		opt = &opts[0]
 	}
 
-	d := drivers.Drivers()
-	if TestDrivers != nil {
-		var newd []drivers.Driver
-		for _, dd := range d {
-			if slices.Contains(TestDrivers, dd.Name()) {
-				newd = append(newd, dd)
-			}
-		}
-		d = newd
-	}
+	d := testDrivers()
 	switch len(d) {
 	case 0:
 		b.Fatal("zdb.RunTest: no registered zdb drivers; you need to import a driver in your test")
@@ -101,6 +83,57 @@ func RunBench(b *testing.B, f func(*testing.B, context.Context), opts ...drivers
 	}
 }
 
+// TestSchemaDiff checks that the database created from the schema file and the
+// database created by running all migrations are identical, for all registered
+// zdb SQL drivers.
+//
+// Every difference is reported with t.Error(); see [SchemaDiff]. This uses two
+// new databases from the driver's StartTest(); opt is only used for the
+// database created from the schema.
+func TestSchemaDiff(t *testing.T, opt drivers.TestOptions) {
+	t.Helper()
+	run := func(t *testing.T, dd drivers.Driver) {
+		t.Helper()
+		createCtx := dd.StartTest(t, &opt)
+		migrateCtx := dd.StartTest(t, nil)
+
+		diff, err := migrateAndDiff(createCtx, migrateCtx, opt.Files, opt.GoMigrations)
+		if err != nil {
+			t.Fatalf("zdb.TestSchemaDiff: %s", err)
+		}
+		for _, d := range diff {
+			t.Error(d)
+		}
+	}
+
+	d := testDrivers()
+	switch len(d) {
+	case 0:
+		t.Fatal("zdb.TestSchemaDiff: no registered zdb drivers; you need to import a driver in your test")
+	case 1:
+		run(t, d[0])
+	default:
+		for _, dd := range d {
+			t.Run(dd.Name(), func(t *testing.T) { run(t, dd) })
+		}
+	}
+}
+
+// testDrivers gets all drivers to test, filtered by TestDrivers.
+func testDrivers() []drivers.Driver {
+	d := drivers.Drivers()
+	if TestDrivers != nil {
+		var newd []drivers.Driver
+		for _, dd := range d {
+			if slices.Contains(TestDrivers, dd.Name()) {
+				newd = append(newd, dd)
+			}
+		}
+		d = newd
+	}
+	return d
+}
+
 type DumpArg int32
 
 func (d DumpArg) has(flag DumpArg) bool { return d&flag != 0 }
@@ -161,7 +194,19 @@ const (
 //	DumpNul        Separate columns with NUL (0x00) bytes; useful to feed output to another printer.
 //	DumpJSON       Show as an array of JSON objects.
 //	DumpHTML       Show as a HTML table.
+//
+// Errors are written to out; use [DumpErr] to get them returned.
 func Dump(ctx context.Context, out io.Writer, query string, params ...any) {
+	dumpImpl(ctx, out, false, query, params...)
+}
+
+// DumpErr is like [Dump], but returns the first error instead of writing it to
+// out; nothing is written after an error.
+func DumpErr(ctx context.Context, out io.Writer, query string, params ...any) error {
+	return dumpImpl(ctx, out, true, query, params...)
+}
+
+func dumpImpl(ctx context.Context, out io.Writer, retErr bool, query string, params ...any) error {
 	var dump DumpArg
 	params = dump.extract(params)
 
@@ -193,9 +238,9 @@ func Dump(ctx context.Context, out io.Writer, query string, params ...any) {
 
 	if dump.has(DumpLocation) {
 		if dump.has(dumpFromLogDB) {
-			fmt.Fprintf(out, "zdb.LogDB: %s\n", bold(zdebug.Loc(5)))
+			fmt.Fprintf(out, "zdb.LogDB: %s\n", bold(zdebug.Loc(6)))
 		} else {
-			fmt.Fprintf(out, "zdb.Dump: %s\n", bold(zdebug.Loc(4)))
+			fmt.Fprintf(out, "zdb.Dump: %s\n", bold(zdebug.Loc(5)))
 		}
 	}
 
@@ -207,16 +252,24 @@ func Dump(ctx context.Context, out io.Writer, query string, params ...any) {
 		var (
 			explain []string
 			err     error
+			query   = query
+			params  = params
 		)
-		switch SQLDialect(ctx) {
+		// Can't prefix "explain" to "load:name".
+		if strings.HasPrefix(query, "load:") {
+			query, params, err = prepareImpl(ctx, MustGetDB(ctx), query, params...)
+		}
+		switch {
+		case err != nil:
+			// Error from prepareImpl.
 		default:
 			err = errors.New("zdb.LogDB: unsupported driver for LogExplain " + SQLDialect(ctx).String())
-		case DialectPostgreSQL:
+		case SQLDialect(ctx) == DialectPostgreSQL:
 			err = Select(ctx, &explain, `explain analyze `+query, params...)
-		case DialectMariaDB:
+		case SQLDialect(ctx) == DialectMariaDB:
 			exp := DumpString(ctx, `explain `+query, params...)
 			explain = []string{exp}
-		case DialectSQLite:
+		case SQLDialect(ctx) == DialectSQLite:
 			var sqe []struct {
 				ID, Parent, Notused int
 				Detail              string
@@ -232,6 +285,9 @@ func Dump(ctx context.Context, out io.Writer, query string, params ...any) {
 				explain[len(sqe)] = "Time: " + ztime.DurationAs(t.Round(time.Microsecond), time.Millisecond) + " ms"
 			}
 		}
+		if err != nil && retErr {
+			return err
+		}
 		if err != nil {
 			section("EXPLAIN", err.Error())
 		} else {
@@ -246,6 +302,7 @@ func Dump(ctx context.Context, out io.Writer, query string, params ...any) {
 			if err != nil {
 				return err
 			}
+			defer rows.Close()
 			cols, err := rows.Columns()
 			if err != nil {
 				return err
@@ -254,19 +311,27 @@ func Dump(ctx context.Context, out io.Writer, query string, params ...any) {
 			switch {
 			default:
 				if dump.has(DumpVertical) {
-					return dumpVertical(buf, rows, cols)
+					err = dumpVertical(buf, rows, cols)
+				} else {
+					err = dumpHorizontal(buf, rows, cols)
 				}
-				return dumpHorizontal(buf, rows, cols)
 			case dump.has(DumpCSV):
-				return dumpCSV(buf, rows, cols)
+				err = dumpCSV(buf, rows, cols)
 			case dump.has(DumpNul):
-				return dumpNul(buf, rows, cols)
+				err = dumpNul(buf, rows, cols)
 			case dump.has(DumpJSON):
-				return dumpJSON(buf, rows, cols)
+				err = dumpJSON(buf, rows, cols)
 			case dump.has(DumpHTML):
-				return dumpHTML(buf, dump.has(DumpVertical), rows, cols)
+				err = dumpHTML(buf, dump.has(DumpVertical), rows, cols)
+			}
+			if err != nil {
+				return err
 			}
+			return rows.Err()
 		}()
+		if err != nil && retErr {
+			return err
+		}
 		if err != nil {
 			section("RESULT", err.Error())
 		} else {
@@ -275,6 +340,7 @@ func Dump(ctx context.Context, out io.Writer, query string, params ...any) {
 	}
 
 	fmt.Fprintln(out)
+	return nil
 }
 
 func dumpHorizontal(buf io.Writer, rows *Rows, cols []string) error {
@@ -0,0 +1,164 @@
+package zdb_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"zgo.at/zdb"
+)
+
+type point struct{ X, Y int }
+
+func init() {
+	zdb.RegisterType(
+		func(d zdb.Dialect, p point) (driver.Value, error) {
+			return fmt.Sprintf("%d,%d", p.X, p.Y), nil
+		},
+		func(src any) (point, error) {
+			var (
+				p   point
+				s   string
+				err error
+			)
+			switch ss := src.(type) {
+			case string:
+				s = ss
+			case []byte:
+				s = string(ss)
+			default:
+				return p, fmt.Errorf("unsupported type: %T", src)
+			}
+			_, err = fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+			return p, err
+		})
+}
+
+func TestRegisterType(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		err := zdb.Exec(ctx, `create table points (id integer, p varchar(20), p2 varchar(20) null)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Positional, pointer, and NULL pointer.
+		p := point{3, 4}
+		err = zdb.Exec(ctx, `insert into points values (?, ?, ?)`, 1, point{1, 2}, &p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, `insert into points values (?, ?, ?)`, 2, point{5, 6}, (*point)(nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Named.
+		err = zdb.Exec(ctx, `insert into points values (:id, :p, null)`, map[string]any{"id": 3, "p": point{7, 8}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		t.Run("struct", func(t *testing.T) {
+			var rows []struct {
+				ID int    `db:"id"`
+				P  point  `db:"p"`
+				P2 *point `db:"p2"`
+			}
+			err := zdb.Select(ctx, &rows, `select * from points order by id`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			have := fmt.Sprintf("%v %v %v %v", rows[0].P, *rows[0].P2, rows[1].P, rows[1].P2)
+			want := "{1 2} {3 4} {5 6} <nil>"
+			if have != want {
+				t.Errorf("\nhave: %s\nwant: %s", have, want)
+			}
+		})
+
+		t.Run("get", func(t *testing.T) {
+			var have point
+			err := zdb.Get(ctx, &have, `select p from points where p = ?`, point{7, 8})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := (point{7, 8}); have != want {
+				t.Errorf("\nhave: %v\nwant: %v", have, want)
+			}
+		})
+
+		t.Run("select", func(t *testing.T) {
+			var have []point
+			err := zdb.Select(ctx, &have, `select p from points where p in (?) order by id`,
+				[]point{{1, 2}, {7, 8}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []point{{1, 2}, {7, 8}}; !reflect.DeepEqual(have, want) {
+				t.Errorf("\nhave: %v\nwant: %v", have, want)
+			}
+		})
+
+		t.Run("query", func(t *testing.T) {
+			rows, err := zdb.Query(ctx, `select id, p from points order by id`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			var have []point
+			for rows.Next() {
+				var (
+					id int
+					p  point
+				)
+				err := rows.Scan(&id, &p)
+				if err != nil {
+					t.Fatal(err)
+				}
+				have = append(have, p)
+			}
+			if want := []point{{1, 2}, {5, 6}, {7, 8}}; !reflect.DeepEqual(have, want) {
+				t.Errorf("\nhave: %v\nwant: %v", have, want)
+			}
+		})
+
+		t.Run("reuse", func(t *testing.T) {
+			// The caller's slices shouldn't be modified.
+			args := []any{4, point{9, 9}, &p}
+			err := zdb.Exec(ctx, `insert into points values (?, ?, ?)`, args...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := args[1].(point); !ok {
+				t.Errorf("args[1] is %T", args[1])
+			}
+			if _, ok := args[2].(*point); !ok {
+				t.Errorf("args[2] is %T", args[2])
+			}
+
+			rows, err := zdb.Query(ctx, `select id, p from points where id = 4`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			var (
+				id   int
+				have point
+				dest = []any{&id, &have}
+			)
+			for rows.Next() {
+				err := rows.Scan(dest...)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if _, ok := dest[1].(*point); !ok {
+				t.Errorf("dest[1] is %T", dest[1])
+			}
+			if want := (point{9, 9}); have != want {
+				t.Errorf("\nhave: %v\nwant: %v", have, want)
+			}
+		})
+	})
+}
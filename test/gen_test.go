@@ -0,0 +1,249 @@
+package zdb_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"zgo.at/zdb"
+	"zgo.at/zdb/drivers"
+	"zgo.at/zstd/ztest"
+)
+
+func TestGenerateStructs(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		for _, q := range []string{
+			`create table sites (
+				site_id     {{auto_increment}},
+				code        {{text 20}}      not null,
+				parent      integer          null,
+				created_at  {{timestamp}}    not null,
+				data        {{blob}}         null
+			)`,
+			`create table site_users (
+				site_id     integer          not null,
+				user_id     integer          not null,
+				primary key (site_id, user_id)
+			)`,
+			`create view active_sites as select * from sites`,
+		} {
+			s, err := zdb.Template(zdb.SQLDialect(ctx), q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = zdb.Exec(ctx, string(s))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		have, err := zdb.GenerateStructs(ctx, zdb.GenerateOptions{Package: "models"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Types differ a bit per database; just check these for SQLite.
+		if zdb.SQLDialect(ctx) != zdb.DialectSQLite {
+			for _, w := range []string{"type Sites struct", "type SiteUsers struct", "`db:\"site_id,id\"`", "Parent    *int64"} {
+				if !strings.Contains(string(have), w) {
+					t.Errorf("doesn't contain %q:\n%s", w, have)
+				}
+			}
+			return
+		}
+
+		want := "// Code generated by zdb; DO NOT EDIT.\n\n" + strings.TrimSpace(`
+package models
+
+import (
+	"time"
+)
+
+// SiteUsers is a row in the "site_users" table.
+type SiteUsers struct {
+	SiteID int64 `+"`db:\"site_id\"`"+`
+	UserID int64 `+"`db:\"user_id\"`"+`
+}
+
+func (SiteUsers) Table() string { return "site_users" }
+
+// Sites is a row in the "sites" table.
+type Sites struct {
+	SiteID    int64     `+"`db:\"site_id,id\"`"+`
+	Code      string    `+"`db:\"code\"`"+`
+	Parent    *int64    `+"`db:\"parent\"`"+`
+	CreatedAt time.Time `+"`db:\"created_at\"`"+`
+	Data      []byte    `+"`db:\"data\"`"+`
+}
+
+func (Sites) Table() string { return "sites" }`) + "\n"
+		if d := ztest.Diff(string(have), want); d != "" {
+			t.Error(d)
+		}
+
+		have, err = zdb.GenerateStructs(ctx, zdb.GenerateOptions{
+			Package: "models",
+			Tables:  []string{"active_sites"},
+			Views:   true,
+			Types:   map[string]string{"blob": "json.RawMessage"},
+			Imports: []string{"encoding/json"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range []string{`"encoding/json"`, "type ActiveSites struct", "Data      json.RawMessage"} {
+			if !strings.Contains(string(have), w) {
+				t.Errorf("doesn't contain %q:\n%s", w, have)
+			}
+		}
+
+		_, err = zdb.GenerateStructs(ctx, zdb.GenerateOptions{Package: "models", Tables: []string{"nope"}})
+		if !ztest.ErrorContains(err, `no such table: "nope"`) {
+			t.Errorf("wrong error: %v", err)
+		}
+	})
+}
+
+func TestGenerateQueries(t *testing.T) {
+	files := fstest.MapFS{
+		"schema.gotxt": {Data: []byte(`
+			create table sites (
+				site_id     {{auto_increment}},
+				code        {{text 20}}      not null,
+				parent      integer          null
+			);`)},
+		"query/select-sites.sql": {Data: []byte(`
+			-- :site_id int64
+			select site_id, code from sites where site_id = :site_id`)},
+		"query/update-site.sql":          {Data: []byte(`update sites set code = :code where site_id = :site_id`)},
+		"query/set-parent.sql":           {Data: []byte(`update sites set parent = :parent where :id = sites.site_id and code like :code`)},
+		"query/insert-site.sql":          {Data: []byte(`insert into sites (code) values (:code) returning site_id, code as new_code`)},
+		"query/bad-returning.sql":        {Data: []byte(`insert into sites (code) values (:code) returning site_id + 1`)},
+		"query/list-codes.gotxt":         {Data: []byte(`select code from sites where {{template "not-root"}} {%:parent and parent = :parent%} {{if .desc}}order by code desc{{end}}`)},
+		"query/_partials/not-root.gotxt": {Data: []byte(`parent != :root`)},
+		"query/list-codes_test.sql":      {Data: []byte(`-- params`)},
+	}
+
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		var skipped []string
+		have, err := zdb.GenerateQueries(ctx, zdb.GenerateOptions{Package: "queries",
+			Skipped: func(q string, err error) { skipped = append(skipped, q) }})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(skipped, []string{"bad-returning"}) {
+			t.Errorf("skipped: %v", skipped)
+		}
+
+		// Types and nullability depend on what the driver reports; go-sqlite3
+		// reports everything as nullable.
+		if zdb.SQLDialect(ctx) != zdb.DialectSQLite {
+			for _, w := range []string{"type ListCodesParams struct", "func UpdateSite(ctx context.Context, p UpdateSiteParams) error",
+				"SiteID int64 `db:\"site_id\"`"} {
+				if !strings.Contains(string(have), w) {
+					t.Errorf("doesn't contain %q:\n%s", w, have)
+				}
+			}
+			return
+		}
+
+		want := "// Code generated by zdb; DO NOT EDIT.\n\n" + strings.TrimSpace(`
+package queries
+
+import (
+	"context"
+
+	"zgo.at/zdb"
+)
+
+// Skipped "bad-returning": can't determine type of "site_id + 1" in returning clause; only columns are supported
+
+// InsertSiteParams are the parameters for the "insert-site" query.
+type InsertSiteParams struct {
+	Code string `+"`db:\"code\"`"+`
+}
+
+// InsertSiteRow is a row returned by the "insert-site" query.
+type InsertSiteRow struct {
+	SiteID  int64  `+"`db:\"site_id\"`"+`
+	NewCode string `+"`db:\"new_code\"`"+`
+}
+
+// InsertSite runs the "insert-site" query.
+func InsertSite(ctx context.Context, p InsertSiteParams) ([]InsertSiteRow, error) {
+	var rows []InsertSiteRow
+	err := zdb.Select(ctx, &rows, "load:insert-site", p)
+	return rows, err
+}
+
+// ListCodesParams are the parameters for the "list-codes" query.
+type ListCodesParams struct {
+	Parent int64 `+"`db:\"parent\"`"+`
+	Root   int64 `+"`db:\"root\"`"+`
+	Desc   any   `+"`db:\"desc\"`"+`
+}
+
+// ListCodesRow is a row returned by the "list-codes" query.
+type ListCodesRow struct {
+	Code *string `+"`db:\"code\"`"+`
+}
+
+// ListCodes runs the "list-codes" query.
+func ListCodes(ctx context.Context, p ListCodesParams) ([]ListCodesRow, error) {
+	var rows []ListCodesRow
+	err := zdb.Select(ctx, &rows, "load:list-codes", p)
+	return rows, err
+}
+
+// SelectSitesParams are the parameters for the "select-sites" query.
+type SelectSitesParams struct {
+	SiteID int64 `+"`db:\"site_id\"`"+`
+}
+
+// SelectSitesRow is a row returned by the "select-sites" query.
+type SelectSitesRow struct {
+	SiteID *int64  `+"`db:\"site_id\"`"+`
+	Code   *string `+"`db:\"code\"`"+`
+}
+
+// SelectSites runs the "select-sites" query.
+func SelectSites(ctx context.Context, p SelectSitesParams) ([]SelectSitesRow, error) {
+	var rows []SelectSitesRow
+	err := zdb.Select(ctx, &rows, "load:select-sites", p)
+	return rows, err
+}
+
+// SetParentParams are the parameters for the "set-parent" query.
+type SetParentParams struct {
+	Parent *int64 `+"`db:\"parent\"`"+`
+	ID     int64  `+"`db:\"id\"`"+`
+	Code   string `+"`db:\"code\"`"+`
+}
+
+// SetParent runs the "set-parent" query.
+func SetParent(ctx context.Context, p SetParentParams) error {
+	return zdb.Exec(ctx, "load:set-parent", p)
+}
+
+// UpdateSiteParams are the parameters for the "update-site" query.
+type UpdateSiteParams struct {
+	Code   string `+"`db:\"code\"`"+`
+	SiteID int64  `+"`db:\"site_id\"`"+`
+}
+
+// UpdateSite runs the "update-site" query.
+func UpdateSite(ctx context.Context, p UpdateSiteParams) error {
+	return zdb.Exec(ctx, "load:update-site", p)
+}`) + "\n"
+		if d := ztest.Diff(string(have), want); d != "" {
+			t.Error(d)
+		}
+
+		_, err = zdb.GenerateQueries(ctx, zdb.GenerateOptions{Package: "queries", Queries: []string{"nope"}})
+		if !ztest.ErrorContains(err, `no such query: "nope"`) {
+			t.Errorf("wrong error: %v", err)
+		}
+	}, drivers.TestOptions{Files: files})
+}
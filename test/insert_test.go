@@ -2,18 +2,25 @@ package zdb_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"zgo.at/zdb"
 	"zgo.at/zstd/ztest"
+	"zgo.at/zstd/ztime"
 )
 
 func testTable(ctx context.Context, t *testing.T) {
 	t.Helper()
-	q := `create table tbl (id serial, str text, "NoTag" text)`
+	q := `create table tbl (id serial, str text, %s text)`
 	if zdb.SQLDialect(ctx) == zdb.DialectSQLite {
-		q = `create table tbl (id integer primary key autoincrement, str text, "NoTag" text)`
+		q = `create table tbl (id integer primary key autoincrement, str text, %s text)`
 	}
+	q = fmt.Sprintf(q, zdb.QuoteIdentifierContext(ctx, "NoTag"))
 	err := zdb.Exec(ctx, q)
 	if err != nil {
 		t.Fatal(err)
@@ -213,3 +220,677 @@ func TestUpdate(t *testing.T) {
 		}
 	})
 }
+
+type upsertRow struct {
+	ID   int    `db:"id,id"`
+	Name string `db:"name"`
+	Val  string `db:"val"`
+	Val2 string `db:"val2"`
+}
+
+func (upsertRow) Table() string { return "upsert_tbl" }
+
+func TestUpsert(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		q := `create table upsert_tbl (id serial primary key, name varchar(100) unique, val text, val2 text)`
+		if zdb.SQLDialect(ctx) == zdb.DialectSQLite {
+			q = `create table upsert_tbl (id integer primary key autoincrement, name varchar(100) unique, val text, val2 text)`
+		}
+		err := zdb.Exec(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, `insert into upsert_tbl (name, val, val2) values ('x', 'x', 'x')`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{ // Insert new row
+			row := upsertRow{Name: "a", Val: "v1", Val2: "w1"}
+			err := zdb.Upsert(ctx, &row, []string{"name"}, "val")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.ID != 2 {
+				t.Fatalf("row.ID is not 2: %d", row.ID)
+			}
+			want := "id  name  val  val2\n1   x     x    x\n2   a     v1   w1\n"
+			if have := zdb.DumpString(ctx, "select * from upsert_tbl order by id"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // Update existing row; only val should be updated.
+			row := upsertRow{Name: "a", Val: "v2", Val2: "w2"}
+			err := zdb.Upsert(ctx, &row, []string{"name"}, "val")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.ID != 2 {
+				t.Fatalf("row.ID is not 2: %d", row.ID)
+			}
+			want := "id  name  val  val2\n1   x     x    x\n2   a     v2   w1\n"
+			if have := zdb.DumpString(ctx, "select * from upsert_tbl order by id"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // UpdateAll
+			row := upsertRow{Name: "a", Val: "v3", Val2: "w3"}
+			err := zdb.Upsert(ctx, &row, []string{"name"}, zdb.UpdateAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.ID != 2 {
+				t.Fatalf("row.ID is not 2: %d", row.ID)
+			}
+			want := "id  name  val  val2\n1   x     x    x\n2   a     v3   w3\n"
+			if have := zdb.DumpString(ctx, "select * from upsert_tbl order by id"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // Conflict on ID
+			row := upsertRow{ID: 1, Name: "y", Val: "y", Val2: "y"}
+			err := zdb.Upsert(ctx, &row, []string{"id"}, zdb.UpdateAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.ID != 1 {
+				t.Fatalf("row.ID is not 1: %d", row.ID)
+			}
+			want := "id  name  val  val2\n1   y     y    y\n2   a     v3   w3\n"
+			if have := zdb.DumpString(ctx, "select * from upsert_tbl order by id"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // Errors
+			row := upsertRow{Name: "a"}
+			err := zdb.Upsert(ctx, row, []string{"name"}, "val")
+			if !ztest.ErrorContains(err, "not a pointer") {
+				t.Fatal(err)
+			}
+			err = zdb.Upsert(ctx, &row, []string{"name"})
+			if !ztest.ErrorContains(err, "no columns") {
+				t.Fatal(err)
+			}
+			err = zdb.Upsert(ctx, &row, nil, "val")
+			if !ztest.ErrorContains(err, "no conflict columns") {
+				t.Fatal(err)
+			}
+			err = zdb.Upsert(ctx, &row, []string{"name"}, "nonexistent")
+			if !ztest.ErrorContains(err, `unknown column or column with ,noinsert: "nonexistent"`) {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+type findRow struct {
+	ID    int    `db:"id,id"`
+	Str   string `db:"str"`
+	NoTag string `db:"NoTag"`
+}
+
+func (findRow) Table() string { return "tbl" }
+
+type findRowPartial struct {
+	ID  int    `db:"id,id"`
+	Str string `db:"str"`
+}
+
+func (findRowPartial) Table() string { return "tbl" }
+
+func TestFindByID(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		testTable(ctx, t)
+
+		err := zdb.Insert(ctx, &findRow{Str: "aaa", NoTag: "bbb"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			var row findRow
+			err := zdb.FindByID(ctx, &row, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := findRow{ID: 1, Str: "aaa", NoTag: "bbb"}
+			if row != want {
+				t.Fatalf("\nhave: %#v\nwant: %#v", row, want)
+			}
+		}
+
+		{ // Only select columns in the struct.
+			var row findRowPartial
+			err := zdb.FindByID(ctx, &row, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := findRowPartial{ID: 1, Str: "aaa"}
+			if row != want {
+				t.Fatalf("\nhave: %#v\nwant: %#v", row, want)
+			}
+		}
+
+		{ // Not found
+			var row findRow
+			err := zdb.FindByID(ctx, &row, 2)
+			if !zdb.ErrNoRows(err) {
+				t.Fatal(err)
+			}
+		}
+
+		{ // Fail on non-ptr
+			err := zdb.FindByID(ctx, findRow{}, 1)
+			if !ztest.ErrorContains(err, "not a pointer") {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		testTable(ctx, t)
+
+		row1, row2 := findRow{Str: "aaa"}, findRow{Str: "bbb"}
+		for _, r := range []*findRow{&row1, &row2} {
+			err := zdb.Insert(ctx, r)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		{
+			err := zdb.Delete(ctx, &row1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "id  str  NoTag\n2   bbb\n"
+			if have := zdb.DumpString(ctx, "select * from tbl"); have != want {
+				t.Fatalf("\n%q", have)
+			}
+		}
+
+		{ // Already deleted
+			err := zdb.Delete(ctx, &row1)
+			if !zdb.ErrNoRows(err) {
+				t.Fatal(err)
+			}
+		}
+
+		{ // ID is zero value
+			err := zdb.Delete(ctx, &findRow{})
+			if !ztest.ErrorContains(err, "zero value") {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestExists(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		testTable(ctx, t)
+
+		row := findRow{Str: "aaa"}
+		err := zdb.Insert(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exists, err := zdb.Exists(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !exists {
+			t.Error("exists is false")
+		}
+
+		exists, err = zdb.Exists(ctx, &findRow{ID: 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists {
+			t.Error("exists is true")
+		}
+
+		_, err = zdb.Exists(ctx, &findRow{})
+		if !ztest.ErrorContains(err, "zero value") {
+			t.Fatal(err)
+		}
+	})
+}
+
+type noIDRow struct {
+	Str string `db:"str"`
+}
+
+func (noIDRow) Table() string { return "tbl" }
+
+func TestInsertAll(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		testTable(ctx, t)
+
+		rows := []*insertRow{{Str: "a", NoTag: "1"}, {Str: "b", NoTag: "2"}, {Str: "c", NoTag: "3"}}
+		err := zdb.InsertAll(ctx, rows)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, r := range rows {
+			if r.ID != i+1 {
+				t.Errorf("row %d: ID is %d", i, r.ID)
+			}
+			if !r.defaultCalled {
+				t.Errorf("row %d: Defaults() not called", i)
+			}
+			if !r.validateCalled {
+				t.Errorf("row %d: Validate() not called", i)
+			}
+		}
+		want := "id  str  NoTag\n1   a    1\n2   b    2\n3   c    3\n"
+		if have := zdb.DumpString(ctx, "select * from tbl"); have != want {
+			t.Fatal("\n" + have)
+		}
+		for _, r := range rows {
+			var have findRow
+			err := zdb.FindByID(ctx, &have, r.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have.Str != r.Str {
+				t.Errorf("ID %d: have %q, want %q", r.ID, have.Str, r.Str)
+			}
+		}
+
+		{ // Without ,id column.
+			err := zdb.InsertAll(ctx, []*noIDRow{{Str: "x"}, {Str: "y"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "str\nx\ny\n"
+			if have := zdb.DumpString(ctx, "select str from tbl where id > 3 order by id"); have != want {
+				t.Fatal("\n" + have)
+			}
+			err = zdb.Exec(ctx, "delete from tbl where id > 3")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		{ // Fail if ID not zero value
+			err := zdb.InsertAll(ctx, []*insertRow{{Str: "d"}, rows[0]})
+			if !ztest.ErrorContains(err, "row 1: id field") {
+				t.Fatal(err)
+			}
+			if have := zdb.DumpString(ctx, "select * from tbl"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // IDs are matched to rows when there are existing rows.
+			more := []*insertRow{{Str: "e"}, {Str: "f"}}
+			err := zdb.InsertAll(ctx, more)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range more {
+				var have findRow
+				err := zdb.FindByID(ctx, &have, r.ID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if have.Str != r.Str {
+					t.Errorf("ID %d: have %q, want %q", r.ID, have.Str, r.Str)
+				}
+			}
+			err = zdb.Exec(ctx, "delete from tbl where id > 3")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		{ // Fail on non-ptr
+			err := zdb.InsertAll(ctx, []insertRow{{Str: "d"}})
+			if !ztest.ErrorContains(err, "not a pointer") {
+				t.Fatal(err)
+			}
+		}
+
+		{ // No rows is not an error.
+			err := zdb.InsertAll(ctx, []*insertRow{})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+}
+
+type prefixRow struct {
+	ID    int       `db:"id,id"`
+	Str   string    `db:"str"`
+	Kids  []noIDRow `db:"kids,prefix=k_"`
+	Other *noIDRow  `db:"other,prefix=o_"`
+}
+
+func (prefixRow) Table() string { return "tbl" }
+
+func TestPrefixFields(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		testTable(ctx, t)
+
+		kids := []noIDRow{{Str: "k"}}
+		row := prefixRow{Str: "a", Kids: kids, Other: &noIDRow{Str: "o"}}
+		err := zdb.Insert(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.InsertAll(ctx, []*prefixRow{{Str: "b", Kids: kids}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		row.Str = "c"
+		err = zdb.Update(ctx, &row, zdb.UpdateAll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zdb.Track(&row)
+		row.Str = "d"
+		err = zdb.UpdateChanged(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var have prefixRow
+		err = zdb.FindByID(ctx, &have, row.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have.Str != "d" || have.Kids != nil || have.Other != nil {
+			t.Errorf("%+v", have)
+		}
+
+		want := "id  str\n1   d\n2   b\n"
+		if have := zdb.DumpString(ctx, "select id, str from tbl order by id"); have != want {
+			t.Fatal("\n" + have)
+		}
+	})
+}
+
+type versionRow struct {
+	ID      int    `db:"id,id"`
+	Str     string `db:"str"`
+	Version int    `db:"version,version"`
+}
+
+func (versionRow) Table() string { return "version_tbl" }
+
+func TestUpdateVersion(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		q := `create table version_tbl (id serial, str text, version int not null)`
+		if zdb.SQLDialect(ctx) == zdb.DialectSQLite {
+			q = `create table version_tbl (id integer primary key autoincrement, str text, version int not null)`
+		}
+		err := zdb.Exec(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		row := versionRow{Str: "a", Version: 1}
+		err = zdb.Insert(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Load a second copy, which will get outdated.
+		var stale versionRow
+		err = zdb.FindByID(ctx, &stale, row.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		{
+			row.Str = "b"
+			err := zdb.Update(ctx, &row, zdb.UpdateAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if row.Version != 2 {
+				t.Fatalf("row.Version is not 2: %d", row.Version)
+			}
+			want := "id  str  version\n1   b    2\n"
+			if have := zdb.DumpString(ctx, "select * from version_tbl"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // Outdated version.
+			stale.Str = "c"
+			err := zdb.Update(ctx, &stale, "str")
+			if !errors.Is(err, zdb.ErrConflict) {
+				t.Fatal(err)
+			}
+			if stale.Version != 1 {
+				t.Fatalf("stale.Version is not 1: %d", stale.Version)
+			}
+			want := "id  str  version\n1   b    2\n"
+			if have := zdb.DumpString(ctx, "select * from version_tbl"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // Explicitly updating the version column is ignored.
+			row.Str = "d"
+			row.Version = 2
+			err := zdb.Update(ctx, &row, "str", "version")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "id  str  version\n1   d    3\n"
+			if have := zdb.DumpString(ctx, "select * from version_tbl"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+	})
+}
+
+type timestampRow struct {
+	ID      int        `db:"id,id"`
+	Str     string     `db:"str"`
+	Created time.Time  `db:"created_at,created"`
+	Updated *time.Time `db:"updated_at,updated"`
+	Deleted *time.Time `db:"deleted_at,deleted"`
+}
+
+func (timestampRow) Table() string { return "ts_tbl" }
+
+func TestTimestamps(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		q := `create table ts_tbl (id serial, str text, created_at timestamp not null, updated_at timestamp, deleted_at timestamp null)`
+		if zdb.SQLDialect(ctx) == zdb.DialectSQLite {
+			q = `create table ts_tbl (id integer primary key autoincrement, str text, created_at timestamp not null, updated_at timestamp, deleted_at timestamp null)`
+		}
+		err := zdb.Exec(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var (
+			t1 = time.Date(2020, 6, 18, 14, 15, 16, 0, time.UTC)
+			t2 = time.Date(2021, 6, 18, 14, 15, 16, 0, time.UTC)
+			t3 = time.Date(2022, 6, 18, 14, 15, 16, 0, time.UTC)
+		)
+
+		row := timestampRow{Str: "a"}
+		err = zdb.Insert(ztime.WithNow(ctx, t1), &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !row.Created.Equal(t1) || row.Updated == nil || !row.Updated.Equal(t1) {
+			t.Fatalf("wrong times after insert: %s; %v", row.Created, row.Updated)
+		}
+
+		row.Str = "b"
+		row.Created = t3
+		err = zdb.Update(ztime.WithNow(ctx, t2), &row, zdb.UpdateAll)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !row.Updated.Equal(t2) {
+			t.Fatalf("wrong updated after update: %v", row.Updated)
+		}
+
+		// Only str in columns; updated_at should still be updated.
+		row.Str = "c"
+		err = zdb.Update(ztime.WithNow(ctx, t3), &row, "str")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var have timestampRow
+		err = zdb.FindByID(ctx, &have, row.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !have.Created.Equal(t1) {
+			t.Errorf("created_at changed: %s", have.Created)
+		}
+		if have.Updated == nil || !have.Updated.Equal(t3) {
+			t.Errorf("wrong updated_at: %v", have.Updated)
+		}
+		if have.Deleted != nil {
+			t.Errorf("deleted_at is set: %v", have.Deleted)
+		}
+	})
+}
+
+func TestSoftDelete(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		q := `create table ts_tbl (id serial, str text, created_at timestamp not null, updated_at timestamp, deleted_at timestamp null)`
+		if zdb.SQLDialect(ctx) == zdb.DialectSQLite {
+			q = `create table ts_tbl (id integer primary key autoincrement, str text, created_at timestamp not null, updated_at timestamp, deleted_at timestamp null)`
+		}
+		err := zdb.Exec(ctx, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Date(2020, 6, 18, 14, 15, 16, 0, time.UTC)
+		ctx = ztime.WithNow(ctx, now)
+
+		row := timestampRow{Str: "a"}
+		err = zdb.Insert(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = zdb.Delete(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.Deleted == nil || !row.Deleted.Equal(now) {
+			t.Fatalf("wrong deleted: %v", row.Deleted)
+		}
+		var n int
+		err = zdb.Get(ctx, &n, `select count(*) from ts_tbl where deleted_at is not null`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 {
+			t.Fatalf("n = %d", n)
+		}
+
+		err = zdb.Delete(ctx, &row)
+		if !zdb.ErrNoRows(err) {
+			t.Fatalf("wrong error: %v", err)
+		}
+
+		err = zdb.HardDelete(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Get(ctx, &n, `select count(*) from ts_tbl`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Fatalf("n = %d", n)
+		}
+	})
+}
+
+type (
+	jsonData struct {
+		Name string   `json:"name"`
+		Tags []string `json:"tags"`
+	}
+	jsonRow struct {
+		ID   int64     `db:"id,id"`
+		Data jsonData  `db:"data,json"`
+		Ptr  *jsonData `db:"ptr,json"`
+	}
+)
+
+func (jsonRow) Table() string { return "json_tbl" }
+
+func TestJSON(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		q, err := zdb.Template(zdb.SQLDialect(ctx),
+			`create table json_tbl (id {{auto_increment}}, data {{json}} not null, ptr {{json}} null)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, string(q))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		row := jsonRow{Data: jsonData{Name: "a", Tags: []string{"x", "y"}}}
+		err = zdb.Insert(ctx, &row)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var get jsonRow
+		err = zdb.FindByID(ctx, &get, row.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(get, row) {
+			t.Errorf("\nhave: %#v\nwant: %#v", get, row)
+		}
+
+		row.Ptr = &jsonData{Name: "p"}
+		err = zdb.Update(ctx, &row, "ptr")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sel []jsonRow
+		err = zdb.Select(ctx, &sel, `select * from json_tbl`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sel) != 1 || !reflect.DeepEqual(sel[0], row) {
+			t.Errorf("\nhave: %#v\nwant: %#v", sel, row)
+		}
+
+		// Named parameters from a struct.
+		err = zdb.Exec(ctx, `update json_tbl set data = :data where id = :id`,
+			jsonRow{ID: row.ID, Data: jsonData{Name: "named"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var name string
+		err = zdb.Get(ctx, &name, `select data from json_tbl`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		name = strings.ReplaceAll(name, " ", "") // PostgreSQL adds spaces.
+		if want := `{"name":"named","tags":null}`; name != want {
+			t.Errorf("\nhave: %s\nwant: %s", name, want)
+		}
+	})
+}
@@ -3,10 +3,14 @@ package zdb_test
 import (
 	"context"
 	"reflect"
+	"slices"
 	"testing"
+	"testing/fstest"
 
 	"zgo.at/zdb"
+	"zgo.at/zdb/drivers"
 	"zgo.at/zdb/test/testdata"
+	"zgo.at/zstd/ztest"
 )
 
 func TestMigrateList(t *testing.T) {
@@ -74,3 +78,47 @@ func TestMigrateList(t *testing.T) {
 		}
 	})
 }
+
+func TestMigrateRollback(t *testing.T) {
+	files := fstest.MapFS{
+		"schema.sql":           {Data: []byte(`create table x (i integer);`)},
+		"migrate/1-a.sql":      {Data: []byte(`create table a (i integer);`)},
+		"migrate/2-b.sql":      {Data: []byte(`create table b (i integer);`)},
+		"migrate/down/1-a.sql": {Data: []byte(`drop table a;`)},
+	}
+
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		m, err := zdb.NewMigrate(zdb.MustGetDB(ctx), files, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = m.Rollback("")
+		if !ztest.ErrorContains(err, `no down migration for "2-b"`) {
+			t.Fatalf("wrong error: %v", err)
+		}
+		err = m.Rollback("1-a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = m.Rollback("1-a")
+		if !ztest.ErrorContains(err, `migration not run: "1-a"`) {
+			t.Fatalf("wrong error: %v", err)
+		}
+
+		_, ran, err := m.List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"2-b"}; !reflect.DeepEqual(ran, want) {
+			t.Errorf("\nhave: %q\nwant: %q", ran, want)
+		}
+		tables, err := zdb.Tables(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.ContainsFunc(tables, func(t zdb.Table) bool { return t.Name == "a" }) {
+			t.Errorf("table a still exists: %v", tables)
+		}
+	}, drivers.TestOptions{Files: files})
+}
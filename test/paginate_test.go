@@ -0,0 +1,151 @@
+package zdb_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"zgo.at/zdb"
+	"zgo.at/zdb/drivers"
+)
+
+func TestPaginate(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		err := zdb.Exec(ctx, `create table page_tbl (id int, grp int, name varchar(10))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 1; i <= 7; i++ {
+			err := zdb.Exec(ctx, `insert into page_tbl values (?, ?, ?)`, i, i%2, fmt.Sprintf("n%d", i))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		type row struct {
+			ID   int    `db:"id"`
+			Grp  int    `db:"grp"`
+			Name string `db:"name"`
+		}
+		ids := func(p zdb.Page[row]) string {
+			var s string
+			for _, r := range p.Rows {
+				s += fmt.Sprintf("%d ", r.ID)
+			}
+			return fmt.Sprintf("%s(next=%t prev=%t)", s, p.Next != "", p.Prev != "")
+		}
+
+		tests := []struct {
+			order  []string
+			params []any
+			query  string
+			want   []string
+		}{
+			{[]string{"id"}, nil, `select * from page_tbl`, []string{
+				"1 2 3 (next=true prev=false)",
+				"4 5 6 (next=true prev=true)",
+				"7 (next=false prev=true)",
+			}},
+			{[]string{"grp desc", "id"}, nil, `select * from page_tbl`, []string{
+				"1 3 5 (next=true prev=false)",
+				"7 2 4 (next=true prev=true)",
+				"6 (next=false prev=true)",
+			}},
+			{[]string{"id desc"}, []any{map[string]any{"grp": 1}}, `select * from page_tbl where grp = :grp`, []string{
+				"7 5 3 (next=true prev=false)",
+				"1 (next=false prev=true)",
+			}},
+			{[]string{"id"}, []any{1}, `select * from page_tbl where grp = ?`, []string{
+				"1 3 5 (next=true prev=false)",
+				"7 (next=false prev=true)",
+			}},
+		}
+
+		for _, tt := range tests {
+			t.Run("", func(t *testing.T) {
+				var (
+					cursor string
+					pages  []zdb.Page[row]
+				)
+				for i := range tt.want {
+					page, err := zdb.Paginate[row](ctx, tt.query, tt.order, cursor, 3, tt.params...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if have := ids(page); have != tt.want[i] {
+						t.Errorf("page %d\nhave: %s\nwant: %s", i, have, tt.want[i])
+					}
+					pages, cursor = append(pages, page), page.Next
+				}
+
+				// And back again.
+				cursor = pages[len(pages)-1].Prev
+				for i := len(tt.want) - 2; i >= 0; i-- {
+					page, err := zdb.Paginate[row](ctx, tt.query, tt.order, cursor, 3, tt.params...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if have := ids(page); have != tt.want[i] {
+						t.Errorf("back page %d\nhave: %s\nwant: %s", i, have, tt.want[i])
+					}
+					cursor = page.Prev
+				}
+			})
+		}
+	})
+}
+
+func TestPaginateLoad(t *testing.T) {
+	files := fstest.MapFS{
+		"schema.sql": {Data: []byte(`
+			create table page_tbl (id int, grp int);
+			insert into page_tbl values (1, 1), (2, 0), (3, 1), (4, 0), (5, 1);`)},
+		"query/page-grp.sql":   {Data: []byte("select * from page_tbl -- by group\nwhere grp = :grp")},
+		"query/page-grp.gotxt": {Data: []byte(`select * from page_tbl where 1=1 {%:grp and grp = :grp%} {{if .odd}}and id % 2 = 1{{end}}`)},
+	}
+
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		type row struct {
+			ID  int `db:"id"`
+			Grp int `db:"grp"`
+		}
+		tests := []struct {
+			query  string
+			params []any
+			want   []string
+		}{
+			{"load:page-grp.sql", []any{map[string]any{"grp": 1}}, []string{"[1 3]", "[5]"}},
+			{"load:page-grp.gotxt", []any{map[string]any{"grp": 1, "odd": true}}, []string{"[1 3]", "[5]"}},
+			{"load:page-grp.gotxt", []any{map[string]any{"grp": nil, "odd": false}}, []string{"[1 2]", "[3 4]", "[5]"}},
+		}
+
+		for _, tt := range tests {
+			t.Run("", func(t *testing.T) {
+				var (
+					cursor string
+					have   []string
+				)
+				for range 5 {
+					page, err := zdb.Paginate[row](ctx, tt.query, []string{"id"}, cursor, 2, tt.params...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					ids := make([]int, 0, len(page.Rows))
+					for _, r := range page.Rows {
+						ids = append(ids, r.ID)
+					}
+					have = append(have, fmt.Sprint(ids))
+					if page.Next == "" {
+						break
+					}
+					cursor = page.Next
+				}
+				if !reflect.DeepEqual(have, tt.want) {
+					t.Errorf("\nhave: %v\nwant: %v", have, tt.want)
+				}
+			})
+		}
+	}, drivers.TestOptions{Files: files})
+}
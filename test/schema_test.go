@@ -0,0 +1,238 @@
+package zdb_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"zgo.at/zdb"
+	"zgo.at/zdb/drivers"
+)
+
+func TestSchema(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		tpl := func(s string) string {
+			t.Helper()
+			q, err := zdb.Template(zdb.SQLDialect(ctx), s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(q)
+		}
+		for _, q := range []string{
+			`create table sites (
+				site_id     {{auto_increment}},
+				code        varchar(20)  not null,
+				parent      integer      null,
+				state       varchar(10)  not null default 'a' check (state in ('a', 'b'))
+			)`,
+			`create unique index sites_code on sites(code)`,
+			`create table users (
+				user_id     {{auto_increment}},
+				site_id     integer      not null,
+				site_code   varchar(20)  not null,
+				email       varchar(50)  null,
+				foreign key (site_id) references sites(site_id) on delete cascade,
+				foreign key (site_code) references sites(code)
+			)`,
+			`create index users_site_email on users(site_id, email)`,
+			`create view active_sites as select * from sites where state = 'a'`,
+		} {
+			err := zdb.Exec(ctx, tpl(q))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		t.Run("tables", func(t *testing.T) {
+			tables, err := zdb.Tables(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []zdb.Table{{Name: "active_sites", View: true}, {Name: "sites"}, {Name: "users"}}
+			if !reflect.DeepEqual(tables, want) {
+				t.Errorf("\nhave: %v\nwant: %v", tables, want)
+			}
+		})
+
+		t.Run("columns", func(t *testing.T) {
+			cols, err := zdb.Columns(ctx, "sites")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cols) != 4 {
+				t.Fatalf("%#v", cols)
+			}
+			if c := cols[0]; c.Name != "site_id" || !c.PrimaryKey || !c.AutoIncrement || c.Nullable {
+				t.Errorf("%#v", c)
+			}
+			if c := cols[1]; c.Name != "code" || c.PrimaryKey || c.AutoIncrement || c.Nullable || c.Default != "" {
+				t.Errorf("%#v", c)
+			}
+			if c := cols[2]; c.Name != "parent" || !c.Nullable {
+				t.Errorf("%#v", c)
+			}
+			if c := cols[3]; c.Name != "state" || c.Nullable || c.Default == "" {
+				t.Errorf("%#v", c)
+			}
+
+			_, err = zdb.Columns(ctx, "doesnt_exist")
+			if err == nil {
+				t.Fatal("err is nil")
+			}
+		})
+
+		t.Run("indexes", func(t *testing.T) {
+			idx, err := zdb.Indexes(ctx, "users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var found bool
+			for _, i := range idx {
+				if i.Name == "users_site_email" {
+					found = true
+					if i.Unique || i.Primary || !reflect.DeepEqual(i.Columns, []string{"site_id", "email"}) {
+						t.Errorf("%#v", i)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("users_site_email not found: %#v", idx)
+			}
+
+			idx, err = zdb.Indexes(ctx, "sites")
+			if err != nil {
+				t.Fatal(err)
+			}
+			found = false
+			for _, i := range idx {
+				if i.Name == "sites_code" {
+					found = true
+					if !i.Unique || i.Primary || !reflect.DeepEqual(i.Columns, []string{"code"}) {
+						t.Errorf("%#v", i)
+					}
+				}
+			}
+			if !found {
+				t.Errorf("sites_code not found: %#v", idx)
+			}
+		})
+
+		t.Run("foreign keys", func(t *testing.T) {
+			fks, err := zdb.ForeignKeys(ctx, "users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fks) != 2 {
+				t.Fatalf("%#v", fks)
+			}
+			for _, fk := range fks {
+				fk.Name = ""
+				switch fk.Columns[0] {
+				case "site_id":
+					want := zdb.ForeignKey{Columns: []string{"site_id"}, RefTable: "sites", RefColumns: []string{"site_id"},
+						OnUpdate: fk.OnUpdate, OnDelete: "CASCADE"}
+					if !reflect.DeepEqual(fk, want) {
+						t.Errorf("\nhave: %#v\nwant: %#v", fk, want)
+					}
+				case "site_code":
+					want := zdb.ForeignKey{Columns: []string{"site_code"}, RefTable: "sites", RefColumns: []string{"code"},
+						OnUpdate: fk.OnUpdate, OnDelete: fk.OnDelete}
+					if !reflect.DeepEqual(fk, want) {
+						t.Errorf("\nhave: %#v\nwant: %#v", fk, want)
+					}
+				default:
+					t.Errorf("%#v", fk)
+				}
+			}
+
+			fks, err = zdb.ForeignKeys(ctx, "sites")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(fks) != 0 {
+				t.Errorf("%#v", fks)
+			}
+		})
+
+		t.Run("checks", func(t *testing.T) {
+			checks, err := zdb.Checks(ctx, "sites")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(checks) != 1 || !strings.Contains(checks[0].Expr, "state") || !strings.Contains(checks[0].Expr, "'b'") {
+				t.Errorf("%#v", checks)
+			}
+
+			checks, err = zdb.Checks(ctx, "users")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(checks) != 0 {
+				t.Errorf("%#v", checks)
+			}
+		})
+	})
+}
+
+func TestSchemaDiff(t *testing.T) {
+	files := func(schema string) fstest.MapFS {
+		return fstest.MapFS{
+			"schema.sql": {Data: []byte(`
+				create table version (name varchar(512));
+				insert into version values ('1-sites'), ('2-code');
+			` + schema)},
+			"migrate/1-sites.sql": {Data: []byte(`
+				create table sites (
+					site_id  integer      primary key,
+					state    varchar(10)  not null default 'a'
+				);`)},
+			"migrate/2-code.sql": {Data: []byte(`
+				alter table sites add column code varchar(20) not null default '';
+				create unique index sites_code on sites(code);`)},
+		}
+	}
+
+	t.Run("same", func(t *testing.T) {
+		zdb.TestSchemaDiff(t, drivers.TestOptions{Files: files(`
+			create table sites (
+				site_id  integer      primary key,
+				code     varchar(20)  not null default '',
+				state    varchar(10)  not null default 'a'
+			);
+			create unique index sites_code on sites(code);
+		`)})
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+			diff, err := zdb.SchemaDiff(ctx, zdb.SchemaDiffOptions{
+				Files: files(`
+					create table sites (
+						site_id  integer      primary key,
+						code     varchar(30)  not null default '',
+						state    varchar(10)  null check (state != '')
+					);
+					create index sites_code on sites(code);
+					create table users (user_id integer primary key);
+				`),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{
+				`table "sites": column "code": type "varchar(30)" in schema, "varchar(20)" in migrations`,
+				`table "sites": column "state": nullable true in schema, false in migrations`,
+				`table "sites": column "state": default "" in schema, "'a'" in migrations`,
+				`table "sites": index "sites_code": unique false in schema, true in migrations`,
+				`table "sites": check "state != ''": only in schema`,
+				`table "users": only in schema`,
+			}
+			if !reflect.DeepEqual(diff, want) {
+				t.Errorf("\nhave: %#v\nwant: %#v", diff, want)
+			}
+		})
+	})
+}
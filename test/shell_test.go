@@ -0,0 +1,278 @@
+package zdb_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"zgo.at/zdb"
+	"zgo.at/zdb/drivers"
+)
+
+func TestShell(t *testing.T) {
+	files := fstest.MapFS{
+		"schema.sql": {Data: []byte(`
+			create table sites (
+				site_id  integer      not null primary key,
+				code     varchar(20)  not null,
+				parent   integer      null
+			);
+			create unique index sites_code on sites(code);
+			insert into sites (site_id, code) values (1, 'one'), (2, 'two words');`)},
+		"query/select-site.sql": {Data: []byte(`select site_id, code from sites where code = :code`)},
+	}
+
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		hist := filepath.Join(t.TempDir(), "history")
+		err := os.WriteFile(hist, []byte("select 0;\n"), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := new(bytes.Buffer)
+		err = zdb.Shell(ctx, zdb.ShellOptions{
+			In: strings.NewReader(`
+select site_id, code
+from sites
+order by site_id;
+\x
+select code from sites where site_id = 1; select code
+from sites where site_id = 2;
+\x
+load:select-site code='two words';
+\format csv
+select site_id from sites order by site_id;
+\format xml
+\nope
+\s
+\q
+select 'not run';
+`),
+			Out:     out,
+			History: hist,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := `
+			site_id  code
+			1        one
+			2        two words
+
+			Vertical output is on.
+			code  one
+
+			code  two words
+
+			Vertical output is off.
+			site_id  code
+			2        two words
+
+			Output format is csv.
+			site_id
+			1
+			2
+
+			unknown format "xml"; must be table, csv, json, or html
+			unknown command: \nope; use \? for help
+			   1  select 0;
+			   2  select site_id, code
+			      from sites
+			      order by site_id;
+			   3  \x
+			   4  select code from sites where site_id = 1;
+			   5  select code
+			      from sites where site_id = 2;
+			   6  \x
+			   7  load:select-site code='two words';
+			   8  \format csv
+			   9  select site_id from sites order by site_id;
+			  10  \format xml
+			  11  \nope
+			  12  \s
+		`
+		if d := zdb.Diff(out.String(), want); d != "" {
+			t.Error(d)
+		}
+
+		h, err := os.ReadFile(hist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := strings.Count(string(h), "\n"); n != 13 {
+			t.Errorf("wrong number of lines in history: %d\n%s", n, h)
+		}
+		if w := "select code\x01from sites where site_id = 2;\n"; !strings.Contains(string(h), w) {
+			t.Errorf("history doesn't contain %q:\n%s", w, h)
+		}
+	}, drivers.TestOptions{Files: files})
+}
+
+type inUseReader struct {
+	io.Reader
+	db    *sql.DB
+	inUse int
+}
+
+func (r *inUseReader) Read(p []byte) (int, error) {
+	r.inUse = max(r.inUse, r.db.Stats().InUse)
+	return r.Reader.Read(p)
+}
+
+func TestShellSession(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		err := zdb.Exec(ctx, `create table t (i integer)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		db, _ := zdb.DBSQL(ctx)
+		in := &inUseReader{db: db, Reader: strings.NewReader(`
+begin;
+insert into t values (1);
+select count(*) as n from t;
+rollback;
+select count(*) as n from t;
+begin;
+insert into t values (2);
+`)}
+		out := new(bytes.Buffer)
+		err = zdb.Shell(ctx, zdb.ShellOptions{In: in, Out: out})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if have, want := strings.Join(strings.Fields(out.String()), " "), "n 1 n 0"; have != want {
+			t.Errorf("\nhave: %q\nwant: %q", have, want)
+		}
+		if in.inUse != 1 {
+			t.Errorf("connections in use: %d", in.inUse)
+		}
+
+		// Transaction left open should be rolled back.
+		var n int
+		err = zdb.Get(ctx, &n, `select count(*) from t`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 0 {
+			t.Errorf("n = %d", n)
+		}
+	})
+}
+
+func TestShellTokenize(t *testing.T) {
+	long := strings.Repeat("x", 100_000)
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		if zdb.SQLDialect(ctx) != zdb.DialectSQLite {
+			t.Skip("backtick quoting and backslashes in strings are SQLite-specific")
+		}
+
+		out := new(bytes.Buffer)
+		err := zdb.Shell(ctx, zdb.ShellOptions{
+			In:  strings.NewReader("select 1 as `a;b`, 'c\\' as d;\nselect length('" + long + "') as n;\n"),
+			Out: out,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := `
+			a;b  d
+			1    c\
+
+			n
+			100000
+		`
+		if d := zdb.Diff(out.String(), want); d != "" {
+			t.Error(d)
+		}
+	})
+}
+
+func TestShellDescribe(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		err := zdb.Exec(ctx, `create table sites (
+			site_id  integer      not null primary key,
+			code     varchar(20)  not null,
+			parent   integer      null
+		)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := new(bytes.Buffer)
+		err = zdb.Shell(ctx, zdb.ShellOptions{
+			In:     strings.NewReader("\\d\n\\d sites\n\\d nope\nselect\n1"),
+			Out:    out,
+			Prompt: "zdb> ",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		have := regexp.MustCompile(`(?m) +$`).ReplaceAllString(out.String(), "")
+		for _, w := range []string{
+			"zdb> name   type\nsites  table\n",
+			"site_id", "code", "parent", "not null",
+			`error: zdb.Columns: table "nope" doesn't exist`,
+			"zdb>   -> ",
+		} {
+			if !strings.Contains(have, w) {
+				t.Errorf("doesn't contain %q:\n%s", w, have)
+			}
+		}
+	})
+}
+
+func TestShellTrigger(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		if zdb.SQLDialect(ctx) == zdb.DialectPostgreSQL {
+			t.Skip("PostgreSQL triggers use a function")
+		}
+		err := zdb.Exec(ctx, `create table t (i integer)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, `create table log (i integer, size varchar(10))`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		out := new(bytes.Buffer)
+		err = zdb.Shell(ctx, zdb.ShellOptions{
+			In: strings.NewReader(`
+create trigger t_log after insert on t for each row
+begin
+	insert into log (i) values (new.i);
+	update log set size = case when new.i > 1 then 'big' else 'small' end where i = new.i;
+end;
+begin;
+insert into t values (1); insert into t values (2);
+commit;
+select i, size from log order by i;
+`),
+			Out: out,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := `
+			i   size
+			1   small
+			2   big
+		`
+		if d := zdb.Diff(out.String(), want); d != "" {
+			t.Error(d)
+		}
+	})
+}
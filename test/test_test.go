@@ -1,6 +1,7 @@
 package zdb_test
 
 import (
+	"bytes"
 	"context"
 	"testing"
 
@@ -113,3 +114,25 @@ func TestDump(t *testing.T) {
 		}
 	})
 }
+
+func TestDumpErr(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		buf := new(bytes.Buffer)
+		err := zdb.DumpErr(ctx, buf, `select 1 as x`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have, want := buf.String(), "x\n1\n\n"; have != want {
+			t.Errorf("\nhave: %q\nwant: %q", have, want)
+		}
+
+		buf.Reset()
+		err = zdb.DumpErr(ctx, buf, `select * from nonexistent`)
+		if err == nil {
+			t.Fatal("err is nil")
+		}
+		if buf.Len() > 0 {
+			t.Errorf("wrote output: %q", buf.String())
+		}
+	})
+}
@@ -2,5 +2,5 @@ package testdata
 
 import "embed"
 
-//go:embed *
+//go:embed all:*
 var Files embed.FS
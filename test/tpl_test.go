@@ -0,0 +1,86 @@
+package zdb_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"zgo.at/zdb"
+)
+
+func TestTemplateSchema(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		tpl := func(s string) string {
+			t.Helper()
+			q, err := zdb.Template(zdb.SQLDialect(ctx), s)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return string(q)
+		}
+		exec := func(s string) error {
+			t.Helper()
+			return zdb.Exec(ctx, tpl(s))
+		}
+
+		err := exec(`
+			create table tpl (
+				tpl_id      {{uuid}}         primary key,
+				name        {{text 50}}      not null,
+				descr       {{text}}         null,
+				status      {{enum "status" "active" "disabled"}} not null,
+				admin       {{bool}}         not null,
+				created_at  {{timestamp}}    not null default {{now}},
+				updated_at  {{timestamptz}}  null
+			)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range 2 {
+			err = exec(`{{create_index_if_not_exists "tpl_name" "tpl" "name" "status"}}`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		id := "0e5b8b71-0c3e-4b9c-9a8c-6a2f2e9b1f6d"
+		for _, name := range []string{"first", "second"} {
+			err = zdb.Exec(ctx, tpl(`insert into tpl (tpl_id, name, status, admin) values (?, ?, ?, ?) {{upsert_clause "tpl_id" "name" "admin"}}`),
+				id, name, "active", name == "second")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = zdb.Exec(ctx, tpl(`insert into tpl (tpl_id, name, status, admin) values (?, ?, ?, ?) {{upsert_clause "tpl_id"}}`),
+			id, "third", "active", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var row struct {
+			ID        string     `db:"tpl_id"`
+			Name      string     `db:"name"`
+			Descr     *string    `db:"descr"`
+			Status    string     `db:"status"`
+			Admin     bool       `db:"admin"`
+			CreatedAt time.Time  `db:"created_at"`
+			UpdatedAt *time.Time `db:"updated_at"`
+		}
+		err = zdb.Get(ctx, &row, `select * from tpl`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if row.ID != id || row.Name != "second" || row.Status != "active" || !row.Admin || row.CreatedAt.IsZero() {
+			t.Errorf("%+v", row)
+		}
+
+		err = zdb.Exec(ctx, `insert into tpl (tpl_id, name, status, admin) values ('8d1c6e2a-5f0b-4a4e-9a71-1b2c3d4e5f60', 'x', 'invalid', false)`)
+		if err == nil {
+			t.Fatal("err is nil")
+		}
+		if !strings.Contains(strings.ToLower(err.Error()), "check") && !strings.Contains(strings.ToLower(err.Error()), "status") {
+			t.Errorf("wrong error: %s", err)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package zdb_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"zgo.at/zdb"
+	"zgo.at/zstd/ztest"
+)
+
+func TestUpdateChanged(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		testTable(ctx, t)
+
+		err := zdb.Insert(ctx, &findRow{Str: "aaa", NoTag: "bbb"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var row findRow
+		err = zdb.UpdateChanged(ctx, &row)
+		if !ztest.ErrorContains(err, "not tracked") {
+			t.Fatal(err)
+		}
+
+		err = zdb.FindByID(ctx, &row, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		zdb.Track(&row)
+
+		{ // Nothing changed: no query should be run.
+			buf := new(bytes.Buffer)
+			err := zdb.UpdateChanged(zdb.WithDB(ctx, zdb.NewLogDB(zdb.MustGetDB(ctx), buf, 0, "")), &row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() > 0 {
+				t.Fatalf("query was run:\n%s", buf.String())
+			}
+		}
+
+		{ // Only the changed column should be written, leaving concurrent changes.
+			err := zdb.Exec(ctx, fmt.Sprintf(`update tbl set %s = 'concurrent'`, zdb.QuoteIdentifierContext(ctx, "NoTag")))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			row.Str = "changed"
+			err = zdb.UpdateChanged(ctx, &row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := "id  str      NoTag\n1   changed  concurrent\n"
+			if have := zdb.DumpString(ctx, "select * from tbl"); have != want {
+				t.Fatal("\n" + have)
+			}
+		}
+
+		{ // Snapshot is updated after UpdateChanged().
+			buf := new(bytes.Buffer)
+			err := zdb.UpdateChanged(zdb.WithDB(ctx, zdb.NewLogDB(zdb.MustGetDB(ctx), buf, 0, "")), &row)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if buf.Len() > 0 {
+				t.Fatalf("query was run:\n%s", buf.String())
+			}
+		}
+	})
+}
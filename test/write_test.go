@@ -0,0 +1,86 @@
+package zdb_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"zgo.at/zdb"
+)
+
+func TestWrite(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		q, err := zdb.Template(zdb.SQLDialect(ctx), `create table write_tbl (
+			id    int,
+			name  varchar(20),
+			data  {{blob}} null,
+			score int null
+		)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, string(q))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, `insert into write_tbl values (?), (?)`,
+			[]any{1, `a "quoted", name`, []byte{0xff, 0x00}, 10},
+			[]any{2, "b", nil, nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		tests := []struct {
+			f    func(context.Context, io.Writer, string, ...any) error
+			want string
+		}{
+			{zdb.WriteJSON, `[
+				{"ID":1,"name":"a \"quoted\", name","data":"/wA=","score":10},
+				{"ID":2,"name":"b","data":null,"score":null}
+			]`},
+			{zdb.WriteNDJSON, `
+				{"ID":1,"name":"a \"quoted\", name","data":"/wA=","score":10}
+				{"ID":2,"name":"b","data":null,"score":null}`},
+			{zdb.WriteCSV, `
+				ID,name,data,score
+				1,"a ""quoted"", name",/wA=,10
+				2,b,,`},
+		}
+
+		for _, tt := range tests {
+			t.Run("", func(t *testing.T) {
+				buf := new(strings.Builder)
+				err := tt.f(ctx, buf, `select * from write_tbl where id > ? order by id`, 0,
+					zdb.RenameColumns{"id": "ID"})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if d := zdb.Diff(buf.String(), tt.want); d != "" {
+					t.Error(d)
+				}
+			})
+		}
+
+		t.Run("no rows", func(t *testing.T) {
+			buf := new(strings.Builder)
+			err := zdb.WriteJSON(ctx, buf, `select * from write_tbl where id = 0`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have := buf.String(); have != "[]\n" {
+				t.Errorf("%q", have)
+			}
+		})
+
+		t.Run("cancel", func(t *testing.T) {
+			cctx, cancel := context.WithCancel(ctx)
+			cancel()
+			err := zdb.WriteNDJSON(cctx, new(strings.Builder), `select * from write_tbl`)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("wrong error: %v", err)
+			}
+		})
+	})
+}
@@ -1,9 +1,14 @@
 package zdb_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"io/fs"
+	"reflect"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	"zgo.at/zdb"
@@ -315,6 +320,157 @@ func TestQuery(t *testing.T) {
 	})
 }
 
+func TestSelectNested(t *testing.T) {
+	type (
+		customer struct {
+			Name string `db:"name"`
+		}
+		tag struct {
+			ID   int    `db:"id,id"`
+			Name string `db:"name"`
+		}
+		order struct {
+			ID       int       `db:"id,id"`
+			Total    int       `db:"total"`
+			Customer *customer `db:"customer,prefix=c_"`
+		}
+		user struct {
+			ID     int     `db:"id,id"`
+			Name   string  `db:"name"`
+			Orders []order `db:"orders,prefix=o_"`
+			Tags   []*tag  `db:"tags,prefix=t_"`
+		}
+	)
+
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		err := zdb.Exec(ctx, `
+			create table users  (id int, name varchar(50));
+			create table orders (id int, user_id int, total int, customer varchar(50));
+			create table tags   (id int, user_id int, name varchar(50));
+			insert into users  values (1, 'Aeryn'), (2, 'Chiana'), (3, 'Crais');
+			insert into orders values (1, 1, 10, 'Moya'), (2, 1, 20, 'Talyn'), (3, 2, 30, 'Moya');
+			insert into tags   values (1, 1, 'a'), (2, 1, 'b');
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var users []user
+		err = zdb.Select(ctx, &users, `
+			select
+				u.id, u.name,
+				o.id as o_id, o.total as o_total, o.customer as c_name,
+				t.id as t_id, t.name as t_name
+			from users u
+			left join orders o on o.user_id = u.id
+			left join tags t on t.user_id = u.id
+			order by u.id, o.id, t.id
+		`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(users) != 3 {
+			t.Fatalf("len(users) = %d", len(users))
+		}
+		{
+			u := users[0]
+			if u.ID != 1 || u.Name != "Aeryn" || len(u.Orders) != 2 || len(u.Tags) != 2 {
+				t.Fatalf("%+v", u)
+			}
+			if u.Orders[0].ID != 1 || u.Orders[0].Total != 10 || u.Orders[0].Customer.Name != "Moya" ||
+				u.Orders[1].ID != 2 || u.Orders[1].Total != 20 || u.Orders[1].Customer.Name != "Talyn" {
+				t.Errorf("%+v", u.Orders)
+			}
+			if u.Tags[0].Name != "a" || u.Tags[1].Name != "b" {
+				t.Errorf("%+v %+v", u.Tags[0], u.Tags[1])
+			}
+		}
+		{
+			u := users[1]
+			if u.ID != 2 || len(u.Orders) != 1 || u.Orders[0].Total != 30 || len(u.Tags) != 0 {
+				t.Errorf("%+v", u)
+			}
+		}
+		{
+			u := users[2]
+			if u.ID != 3 || len(u.Orders) != 0 || len(u.Tags) != 0 {
+				t.Errorf("%+v", u)
+			}
+		}
+
+		{ // Get
+			query := `
+				select
+					u.id, u.name,
+					o.id as o_id, o.total as o_total, o.customer as c_name,
+					t.id as t_id, t.name as t_name
+				from users u
+				left join orders o on o.user_id = u.id
+				left join tags t on t.user_id = u.id
+				where u.id = ?
+				order by u.id, o.id, t.id`
+
+			var u user
+			err := zdb.Get(ctx, &u, query, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if u.ID != 1 || u.Name != "Aeryn" || len(u.Orders) != 2 || len(u.Tags) != 2 {
+				t.Fatalf("%+v", u)
+			}
+			if u.Orders[1].Total != 20 || u.Orders[1].Customer.Name != "Talyn" || u.Tags[1].Name != "b" {
+				t.Errorf("%+v", u)
+			}
+
+			err = zdb.Get(ctx, &u, query, 42)
+			if !zdb.ErrNoRows(err) {
+				t.Fatal(err)
+			}
+		}
+
+		{ // []byte IDs and time.Time in the nested struct.
+			type (
+				event struct {
+					At time.Time `db:"at"`
+				}
+				blobUser struct {
+					ID     []byte  `db:"id,id"`
+					Events []event `db:"events,prefix=e_"`
+				}
+			)
+			err := zdb.Exec(ctx, `
+				create table events (user_id int, at timestamp);
+				insert into events values (1, '2020-06-18 12:00:00'), (1, '2020-06-19 12:00:00');
+			`)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			blob := "blob"
+			switch zdb.SQLDialect(ctx) {
+			case zdb.DialectPostgreSQL:
+				blob = "bytea"
+			case zdb.DialectMariaDB:
+				blob = "binary"
+			}
+			var have []blobUser
+			err = zdb.Select(ctx, &have, `
+				select cast(u.name as `+blob+`) as id, e.at as e_at
+				from users u
+				join events e on e.user_id = u.id
+				order by e.at`)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(have) != 1 || string(have[0].ID) != "Aeryn" || len(have[0].Events) != 2 ||
+				have[0].Events[1].At.Format("2006-01-02") != "2020-06-19" {
+				t.Errorf("%+v", have)
+			}
+		}
+	})
+}
+
 func TestLoad(t *testing.T) {
 	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
 		db := zdb.MustGetDB(ctx)
@@ -345,6 +501,104 @@ func TestLoad(t *testing.T) {
 	}, drivers.TestOptions{Files: testdata.Files})
 }
 
+func TestLoadTemplate(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		tests := []struct {
+			params map[string]any
+			want   []string
+		}{
+			{map[string]any{"faction": 1, "status": ""},
+				[]string{"Aeryn", "Crais"}},
+			{map[string]any{"faction": 2, "species": 7, "status": ""},
+				nil},
+			{map[string]any{"faction": 2, "status": "dead"},
+				[]string{"Zhaan"}},
+			{map[string]any{"faction": 2, "status": "", "desc": true},
+				[]string{"Zhaan", "Stark", "Rygel", "Pilot", "D'argo", "Crichton", "Chiana"}},
+			{map[string]any{"faction": 1, "species": 7, "status": "alive", "desc": true},
+				[]string{"Crais", "Aeryn"}},
+		}
+
+		for _, tt := range tests {
+			t.Run("", func(t *testing.T) {
+				for range 2 { // Run twice to test the cache.
+					var have []string
+					err := zdb.Select(ctx, &have, "load:select-people", tt.params)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !reflect.DeepEqual(have, tt.want) {
+						t.Errorf("\nhave: %q\nwant: %q", have, tt.want)
+					}
+				}
+			})
+		}
+
+		t.Run("query", func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			err := zdb.Exec(ctx, "load:select-people", map[string]any{"faction": 1, "status": ""}, zdb.DumpQuery, buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			have := strings.TrimSpace(buf.String())
+			want := "/* select-people */\nselect name from people\nwhere\n\tfaction_id = 1\norder by name;"
+			if have != want {
+				t.Errorf("\nhave: %q\nwant: %q", have, want)
+			}
+		})
+	}, drivers.TestOptions{Files: testdata.Files})
+}
+
+// countFS counts how often files are opened.
+type countFS struct {
+	fs.FS
+	opened map[string]int
+}
+
+func (c countFS) Open(name string) (fs.File, error) {
+	c.opened[name]++
+	return c.FS.Open(name)
+}
+
+func TestLoadTemplateCache(t *testing.T) {
+	files := fstest.MapFS{
+		"schema.sql":                {Data: []byte(`create table x (i int);`)},
+		"query/tpl.gotxt":           {Data: []byte(`select {{template "col"}}`)},
+		"query/_partials/col.gotxt": {Data: []byte(`1 as x`)},
+	}
+	fsys := countFS{FS: files, opened: make(map[string]int)}
+
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		for range 3 {
+			var have string
+			err := zdb.Get(ctx, &have, "load:tpl")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if have != "1" {
+				t.Fatalf("have: %q", have)
+			}
+		}
+		if n := fsys.opened["query/_partials/col.gotxt"]; n != 1 {
+			t.Errorf("partial read %d times", n)
+		}
+
+		// Changes to the query are picked up.
+		files["query/tpl.gotxt"] = &fstest.MapFile{Data: []byte(`select {{template "col"}}, 2 as y`)}
+		var have struct {
+			X int `db:"x"`
+			Y int `db:"y"`
+		}
+		err := zdb.Get(ctx, &have, "load:tpl")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have.X != 1 || have.Y != 2 {
+			t.Errorf("have: %v", have)
+		}
+	}, drivers.TestOptions{Files: fsys})
+}
+
 func TestBegin(t *testing.T) {
 	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
 		txctx, tx, err := zdb.Begin(ctx)
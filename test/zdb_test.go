@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 
@@ -132,6 +133,89 @@ func TestMissingFields(t *testing.T) {
 	})
 }
 
+func TestStrict(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		err := zdb.Exec(ctx, `create table t (a text, b text)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, `insert into t values ('1', '2')`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		type (
+			exact struct {
+				A string `db:"a"`
+				B string `db:"b"`
+			}
+			missingField struct {
+				A string `db:"a"`
+			}
+			missingColumn struct {
+				A string `db:"a"`
+				B string `db:"b"`
+				C string `db:"c"`
+			}
+		)
+
+		{ // Exact match is always fine.
+			var r []exact
+			err := zdb.Select(ctx, &r, `select * from t`, zdb.StrictAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var g exact
+			err = zdb.Get(ctx, &g, `select * from t`, zdb.StrictAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		{
+			var r []missingField
+			err := zdb.Select(ctx, &r, `select * from t`, zdb.StrictColumns)
+			if !zdb.ErrMissingField(err) {
+				t.Errorf("wrong error: %#v", err)
+			}
+			if len(r) != 0 {
+				t.Errorf("scanned rows: %v", r)
+			}
+
+			// Extra fields are fine with only StrictColumns.
+			var g missingColumn
+			err = zdb.Get(ctx, &g, `select * from t`, zdb.StrictColumns)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		{
+			var g missingColumn
+			err := zdb.Get(ctx, &g, `select * from t`, zdb.StrictFields)
+			if !zdb.ErrMissingColumn(err) {
+				t.Errorf("wrong error: %#v", err)
+			}
+			if g.A != "" {
+				t.Errorf("scanned row: %v", g)
+			}
+
+			rows, err := zdb.Query(ctx, `select a from t`, zdb.StrictFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var e exact
+				err := rows.Scan(&e)
+				if !zdb.ErrMissingColumn(err) {
+					t.Errorf("wrong error: %#v", err)
+				}
+			}
+		}
+	})
+}
+
 func TestSelect(t *testing.T) {
 	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
 		err := zdb.Exec(ctx, `create table t (a text, b text, c text, d text)`)
@@ -260,3 +344,74 @@ func TestNilPanic(t *testing.T) {
 		}
 	})
 }
+
+func TestOrderBy(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		err := zdb.Exec(ctx, `create table t (name varchar(10), n int)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = zdb.Exec(ctx, `insert into t values ('a', 2), ('b', 1), ('c', 2)`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		allowed := map[string]string{"name": "", "n": ""}
+		tests := []struct {
+			order string
+			want  []string
+		}{
+			{"name", []string{"a", "b", "c"}},
+			{"-name", []string{"c", "b", "a"}},
+			{"n,-name", []string{"b", "c", "a"}},
+			{"n desc, name", []string{"a", "c", "b"}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.order, func(t *testing.T) {
+				var have []string
+				err := zdb.Select(ctx, &have, `select ? from t order by ?`,
+					zdb.Ident("name"), zdb.OrderBy(tt.order, allowed))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(have, tt.want) {
+					t.Errorf("\nhave: %v\nwant: %v", have, tt.want)
+				}
+			})
+		}
+
+		var have []string
+		err = zdb.Select(ctx, &have, `select name from t order by :order`,
+			map[string]any{"order": zdb.OrderBy("name; drop table t", allowed)})
+		if !errors.Is(err, zdb.ErrOrderBy) {
+			t.Fatalf("wrong error: %v", err)
+		}
+	})
+}
+
+func TestQuoteIdentifierContext(t *testing.T) {
+	zdb.RunTest(t, func(t *testing.T, ctx context.Context) {
+		tbl, col := zdb.QuoteIdentifierContext(ctx, "order"), zdb.QuoteIdentifierContext(ctx, "group")
+		err := zdb.Exec(ctx, fmt.Sprintf(`create table %s (%s varchar(10))`, tbl, col))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		b := zdb.NewBulkInsert(ctx, "order", []string{col})
+		b.Values("a")
+		b.Values("b")
+		err = b.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var have []string
+		err = zdb.Select(ctx, &have, fmt.Sprintf(`select %s from %s order by %[1]s`, col, tbl))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"a", "b"}; !reflect.DeepEqual(have, want) {
+			t.Errorf("\nhave: %v\nwant: %v", have, want)
+		}
+	})
+}
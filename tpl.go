@@ -3,8 +3,12 @@ package zdb
 import (
 	"bytes"
 	"fmt"
+	"io/fs"
+	"path"
 	"reflect"
 	"regexp"
+	"strings"
+	"sync"
 	"text/template"
 
 	"zgo.at/zstd/zcrypto"
@@ -30,6 +34,29 @@ var TemplateFuncMap template.FuncMap
 //	                                bigserial/bigint.
 //	{{json}}                        JSON column type (jsonb, json, varchar)
 //	{{blob}}                        Binary column type (bytea, blob, binary)
+//	{{timestamp}}                   Timestamp without timezone (timestamp,
+//	                                datetime)
+//	{{timestamptz}}                 Timestamp with timezone (timestamptz,
+//	                                timestamp, datetime); only PostgreSQL
+//	                                stores the timezone.
+//	{{bool}}                        Boolean (boolean, integer, boolean)
+//	{{uuid}}                        UUID (uuid, varchar, char(36))
+//	{{text [n]}}                    Text; with [n] it's varchar(n).
+//	{{enum "col" "a" "b" ..}}       Column that only allows the given values;
+//	                                this uses a check constraint on PostgreSQL
+//	                                and SQLite, and an enum on MariaDB.
+//
+// Other SQL:
+//
+//	{{now}}                         Current time, for defaults.
+//	{{create_index_if_not_exists "name" "table" "col" ..}}
+//	                                Create an index if it doesn't exist yet.
+//	{{upsert_clause "conflict" "col" ..}}
+//	                                Update the columns if the insert
+//	                                conflicts with a unique constraint on the
+//	                                conflict column(s); "conflict" can be a
+//	                                comma-separated list. This does nothing
+//	                                if no columns are given.
 //
 // These only produce output for SQLite:
 //
@@ -39,11 +66,127 @@ var TemplateFuncMap template.FuncMap
 // You can set additional functions (or override any of the above) by adding
 // functions to [TemplateFuncMap].
 func Template(dialect Dialect, tpl string, params ...any) ([]byte, error) {
+	paramMap, err := templateParams(params)
+	if err != nil {
+		return nil, fmt.Errorf("zdb.Template: %w", err)
+	}
+
+	t, err := template.New("").Funcs(tplFuncs(dialect)).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("zdb.Template: %w", err)
+	}
+
+	b, err := execTemplate(t, paramMap)
+	if err != nil {
+		return nil, fmt.Errorf("zdb.Template: %w", err)
+	}
+	return b, nil
+}
+
+// tplCache caches the partials and parsed query templates for a connection.
+type tplCache struct {
+	partialsOnce sync.Once
+	partials     *template.Template // Only the partials; cloned for every query.
+	partialsErr  error
+
+	mu   sync.Mutex
+	tpls map[string]cachedTpl // Keyed by query name.
+}
+
+type cachedTpl struct {
+	src string // Query source, so that changes on the filesystem are picked up.
+	tpl *template.Template
+}
+
+// loadPartials parses all templates in the "_partials" directory.
+func loadPartials(fsys fs.FS, dialect Dialect) (*template.Template, error) {
+	tpl := template.New("").Funcs(tplFuncs(dialect))
+	if fsys == nil {
+		return tpl, nil
+	}
+	partials, err := fs.Glob(fsys, "_partials/*.gotxt")
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range partials {
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return nil, err
+		}
+		// Trailing newline is almost always just the end of the file.
+		b = bytes.TrimRight(b, "\n")
+		n := strings.TrimSuffix(path.Base(p), ".gotxt")
+		_, err = tpl.New(n).Parse(string(b))
+		if err != nil {
+			return nil, fmt.Errorf("partial %q: %w", n, err)
+		}
+	}
+	return tpl, nil
+}
+
+// queryTemplate runs the template for the query name loaded from a .gotxt file,
+// with the partials from the "_partials" directory.
+//
+// The partials are read once per connection, and the parsed template is cached
+// per query name until the query source changes.
+func queryTemplate(db DB, name, query string, params any) (string, error) {
+	var p []any
+	if params != nil {
+		p = []any{params}
+	}
+	paramMap, err := templateParams(p)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		dialect = db.SQLDialect()
+		fsys    = db.(interface{ queryFiles() fs.FS }).queryFiles()
+		cache   *tplCache
+	)
+	if c, ok := Unwrap(db).(interface{ queryTemplates() *tplCache }); ok {
+		cache = c.queryTemplates()
+	}
+	if cache == nil {
+		cache = new(tplCache)
+	}
+
+	cache.mu.Lock()
+	c, ok := cache.tpls[name]
+	cache.mu.Unlock()
+	if !ok || c.src != query {
+		cache.partialsOnce.Do(func() { cache.partials, cache.partialsErr = loadPartials(fsys, dialect) })
+		if cache.partialsErr != nil {
+			return "", cache.partialsErr
+		}
+		tpl, err := cache.partials.Clone()
+		if err != nil {
+			return "", err
+		}
+		_, err = tpl.Parse(query)
+		if err != nil {
+			return "", err
+		}
+
+		c = cachedTpl{src: query, tpl: tpl}
+		cache.mu.Lock()
+		if cache.tpls == nil {
+			cache.tpls = make(map[string]cachedTpl)
+		}
+		cache.tpls[name] = c
+		cache.mu.Unlock()
+	}
+
+	b, err := execTemplate(c.tpl, paramMap)
+	return string(b), err
+}
+
+func templateParams(params []any) (map[string]any, error) {
 	paramMap := map[string]any{}
 	for _, param := range params {
 		v := reflect.ValueOf(param)
 		if !v.IsValid() {
-			return nil, fmt.Errorf("zdb.Template: invalid template parameter type %#v", param)
+			return nil, fmt.Errorf("invalid template parameter type %#v", param)
 		}
 
 		for v = reflect.ValueOf(param); v.Kind() == reflect.Ptr; {
@@ -66,19 +209,18 @@ func Template(dialect Dialect, tpl string, params ...any) ([]byte, error) {
 			}
 		}
 	}
+	return paramMap, nil
+}
 
-	t, err := template.New("").Funcs(tplFuncs(dialect)).Parse(tpl)
-	if err != nil {
-		return nil, fmt.Errorf("zdb.Template: %w", err)
-	}
+var reTrailingSpace = regexp.MustCompile(` +\n`)
 
+func execTemplate(t *template.Template, params map[string]any) ([]byte, error) {
 	buf := new(bytes.Buffer)
-	err = t.Execute(buf, paramMap)
+	err := t.Execute(buf, params)
 	if err != nil {
-		return nil, fmt.Errorf("zdb.Template: %w", err)
+		return nil, err
 	}
-	b := regexp.MustCompile(` +\n`).ReplaceAll(buf.Bytes(), []byte("\n"))
-	return b, nil
+	return reTrailingSpace.ReplaceAll(buf.Bytes(), []byte("\n")), nil
 }
 
 func tplFuncs(dialect Dialect) template.FuncMap {
@@ -124,6 +266,81 @@ func tplFuncs(dialect Dialect) template.FuncMap {
 				DialectSQLite: "check(" + col + " = strftime('%Y-%m-%d', " + col + "))",
 			}[dialect]
 		},
+		"timestamp": func() string {
+			return map[Dialect]string{
+				DialectPostgreSQL: "timestamp",
+				DialectSQLite:     "timestamp",
+				DialectMariaDB:    "datetime",
+			}[dialect]
+		},
+		"timestamptz": func() string {
+			return map[Dialect]string{
+				DialectPostgreSQL: "timestamptz",
+				DialectSQLite:     "timestamp",
+				DialectMariaDB:    "datetime",
+			}[dialect]
+		},
+		"bool": func() string {
+			return map[Dialect]string{
+				DialectPostgreSQL: "boolean",
+				DialectSQLite:     "integer",
+				DialectMariaDB:    "boolean",
+			}[dialect]
+		},
+		"uuid": func() string {
+			return map[Dialect]string{
+				DialectPostgreSQL: "uuid",
+				DialectSQLite:     "varchar",
+				DialectMariaDB:    "char(36)",
+			}[dialect]
+		},
+		"text": func(n ...int) string {
+			if len(n) > 0 {
+				return fmt.Sprintf("varchar(%d)", n[0])
+			}
+			return "text"
+		},
+		"enum": func(col string, vals ...string) string {
+			q := make([]string, 0, len(vals))
+			for _, v := range vals {
+				q = append(q, "'"+strings.ReplaceAll(v, "'", "''")+"'")
+			}
+			if dialect == DialectMariaDB {
+				return "enum(" + strings.Join(q, ", ") + ")"
+			}
+			return "varchar check(" + col + " in (" + strings.Join(q, ", ") + "))"
+		},
+		"now": func() string {
+			return map[Dialect]string{
+				DialectPostgreSQL: "now()",
+				DialectSQLite:     "current_timestamp",
+				DialectMariaDB:    "current_timestamp",
+			}[dialect]
+		},
+		"create_index_if_not_exists": func(name, table string, cols ...string) string {
+			return "create index if not exists " + name + " on " + table + " (" + strings.Join(cols, ", ") + ")"
+		},
+		"upsert_clause": func(conflict string, cols ...string) string {
+			set := make([]string, 0, len(cols))
+			for _, c := range cols {
+				if dialect == DialectMariaDB {
+					set = append(set, c+" = values("+c+")")
+				} else {
+					set = append(set, c+" = excluded."+c)
+				}
+			}
+			if dialect == DialectMariaDB {
+				if len(set) == 0 { // No "do nothing" in MariaDB; set a column to itself.
+					c := strings.TrimSpace(strings.Split(conflict, ",")[0])
+					set = append(set, c+" = "+c)
+				}
+				return "on duplicate key update " + strings.Join(set, ", ")
+			}
+			if len(set) == 0 {
+				return "on conflict (" + conflict + ") do nothing"
+			}
+			return "on conflict (" + conflict + ") do update set " + strings.Join(set, ", ")
+		},
 		"random_text": func(n int) string {
 			return zcrypto.SecretString(n, "")
 		},
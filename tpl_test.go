@@ -51,3 +51,82 @@ PSQL
 		})
 	}
 }
+
+func TestTemplateTypes(t *testing.T) {
+	const testSchema = `
+create table x (
+	x_id        {{uuid}}         primary key,
+	name        {{text}}         not null,
+	code        {{text 10}}      not null,
+	status      {{enum "status" "a" "it's"}} not null,
+	active      {{bool}}         not null,
+	created_at  {{timestamp}}    not null default {{now}},
+	updated_at  {{timestamptz}}  null
+);
+{{create_index_if_not_exists "x_name" "x" "name" "code"}};
+insert into x (x_id, name) values ('1', 'a') {{upsert_clause "x_id" "name" "code"}};
+insert into x (x_id, name) values ('1', 'a') {{upsert_clause "x_id, name"}};
+`
+
+	tests := []struct {
+		driver Dialect
+		want   string
+	}{
+		{DialectSQLite, `
+create table x (
+	x_id        varchar         primary key,
+	name        text         not null,
+	code        varchar(10)      not null,
+	status      varchar check(status in ('a', 'it''s')) not null,
+	active      integer         not null,
+	created_at  timestamp    not null default current_timestamp,
+	updated_at  timestamp  null
+);
+create index if not exists x_name on x (name, code);
+insert into x (x_id, name) values ('1', 'a') on conflict (x_id) do update set name = excluded.name, code = excluded.code;
+insert into x (x_id, name) values ('1', 'a') on conflict (x_id, name) do nothing;
+`},
+		{DialectPostgreSQL, `
+create table x (
+	x_id        uuid         primary key,
+	name        text         not null,
+	code        varchar(10)      not null,
+	status      varchar check(status in ('a', 'it''s')) not null,
+	active      boolean         not null,
+	created_at  timestamp    not null default now(),
+	updated_at  timestamptz  null
+);
+create index if not exists x_name on x (name, code);
+insert into x (x_id, name) values ('1', 'a') on conflict (x_id) do update set name = excluded.name, code = excluded.code;
+insert into x (x_id, name) values ('1', 'a') on conflict (x_id, name) do nothing;
+`},
+		{DialectMariaDB, `
+create table x (
+	x_id        char(36)         primary key,
+	name        text         not null,
+	code        varchar(10)      not null,
+	status      enum('a', 'it''s') not null,
+	active      boolean         not null,
+	created_at  datetime    not null default current_timestamp,
+	updated_at  datetime  null
+);
+create index if not exists x_name on x (name, code);
+insert into x (x_id, name) values ('1', 'a') on duplicate key update name = values(name), code = values(code);
+insert into x (x_id, name) values ('1', 'a') on duplicate key update x_id = x_id;
+`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver.String(), func(t *testing.T) {
+			got, err := Template(tt.driver, testSchema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = bytes.TrimSpace(got)
+			tt.want = strings.TrimSpace(tt.want)
+			if string(got) != tt.want {
+				t.Errorf("\ngot:\n%s\nwant:\n%s", string(got), tt.want)
+			}
+		})
+	}
+}
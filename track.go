@@ -0,0 +1,106 @@
+package zdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+	"slices"
+	"sync"
+	"weak"
+)
+
+// Snapshots from Track(); the key is a weak.Pointer to the tracked value, and
+// the entry is removed once the value is garbage collected.
+var tracked = struct {
+	mu sync.Mutex
+	m  map[any][]any
+}{m: make(map[any][]any)}
+
+// Track records the current values of t, so that [UpdateChanged] can update
+// only the columns that were modified.
+//
+// This is typically called after loading a row:
+//
+//	var site Site
+//	err := zdb.FindByID(ctx, &site, id)
+//	zdb.Track(&site)
+//
+//	site.Name = "new name"
+//	err = zdb.UpdateChanged(ctx, &site)
+//
+// Values are compared with reflect.DeepEqual(). Note that only the field
+// values are copied: pointers, slices, and maps should be replaced rather than
+// modified in-place, or the changes won't be detected.
+//
+// Calling Track() again will replace the snapshot.
+func Track[T any, PT interface {
+	*T
+	Tabler
+}](t PT) {
+	_, vals, _ := columnFields(t, "")
+	key := weak.Make((*T)(t))
+
+	tracked.mu.Lock()
+	_, ok := tracked.m[key]
+	tracked.m[key] = vals
+	tracked.mu.Unlock()
+
+	if !ok {
+		runtime.AddCleanup((*T)(t), func(k weak.Pointer[T]) {
+			tracked.mu.Lock()
+			delete(tracked.m, k)
+			tracked.mu.Unlock()
+		}, key)
+	}
+}
+
+// UpdateChanged updates all columns that were modified since [Track] was
+// called.
+//
+// This won't run any query if nothing was changed. Fields with the ",id",
+// ",noinsert", ",readonly", or ",created" options are never updated. See
+// [Update] for other details.
+//
+// The snapshot is updated on success, so UpdateChanged() can be called again
+// after further modifications.
+func UpdateChanged[T any, PT interface {
+	*T
+	Tabler
+}](ctx context.Context, t PT) error {
+	key := weak.Make((*T)(t))
+	tracked.mu.Lock()
+	snap, ok := tracked.m[key]
+	tracked.mu.Unlock()
+	if !ok {
+		return errors.New("zdb.UpdateChanged: t is not tracked; call zdb.Track() first")
+	}
+
+	cols, vals, opts := columnFields(t, "")
+	if len(cols) != len(snap) {
+		return fmt.Errorf("zdb.UpdateChanged: snapshot has %d columns but t has %d", len(snap), len(cols))
+	}
+
+	var changed []string
+	for i := range cols {
+		if slices.ContainsFunc(opts[i], func(o string) bool {
+			return o == "id" || o == "noinsert" || o == "readonly" || o == "created" || o == "version" || o == "updated"
+		}) {
+			continue
+		}
+		if !reflect.DeepEqual(snap[i], vals[i]) {
+			changed = append(changed, cols[i])
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	err := Update(ctx, t, changed...)
+	if err != nil {
+		return err
+	}
+	Track(t)
+	return nil
+}
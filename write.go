@@ -0,0 +1,314 @@
+package zdb
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RenameColumns renames columns in the output of [WriteJSON], [WriteNDJSON],
+// and [WriteCSV]; the key is the column name from the query, and the value the
+// name to use in the output.
+//
+// This is passed as a parameter, and isn't sent to the database:
+//
+//	zdb.WriteCSV(ctx, w, `select * from users where site_id = ?`, siteID,
+//	    zdb.RenameColumns{"user_id": "ID", "email": "Email address"})
+type RenameColumns map[string]string
+
+type writeFormat uint8
+
+const (
+	writeJSON writeFormat = iota
+	writeNDJSON
+	writeCSV
+)
+
+// WriteJSON runs the query and writes the result as a JSON array of objects to
+// w.
+//
+// Rows are written as they're read from the database, so this uses a constant
+// amount of memory regardless of the number of rows. The key order in the
+// objects is the column order.
+//
+// Values are formatted as:
+//
+//	NULL             null
+//	time             string in RFC 3339 format
+//	binary columns   base64-encoded string (blob, bytea, binary, varbinary)
+//	number columns   number (integer, float, and numeric/decimal types)
+//	boolean columns  true or false
+//	other            the JSON type that matches the value
+//
+// The column type is used because not all drivers return typed values; for
+// example MariaDB returns most values as []byte. This way the output is the
+// same for all databases.
+//
+// Columns can be renamed by adding [RenameColumns] to the parameters. This
+// stops writing and returns an error if ctx is cancelled, which may leave the
+// output incomplete.
+func WriteJSON(ctx context.Context, w io.Writer, query string, params ...any) error {
+	err := writeRows(ctx, w, writeJSON, query, params)
+	if err != nil {
+		return fmt.Errorf("zdb.WriteJSON: %w", err)
+	}
+	return nil
+}
+
+// WriteNDJSON runs the query and writes the result as newline-delimited JSON to
+// w, with one object per row.
+//
+// See [WriteJSON] for details.
+func WriteNDJSON(ctx context.Context, w io.Writer, query string, params ...any) error {
+	err := writeRows(ctx, w, writeNDJSON, query, params)
+	if err != nil {
+		return fmt.Errorf("zdb.WriteNDJSON: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV runs the query and writes the result as CSV to w, with the column
+// names in the first line.
+//
+// NULL is written as an empty string. See [WriteJSON] for other details.
+func WriteCSV(ctx context.Context, w io.Writer, query string, params ...any) error {
+	err := writeRows(ctx, w, writeCSV, query, params)
+	if err != nil {
+		return fmt.Errorf("zdb.WriteCSV: %w", err)
+	}
+	return nil
+}
+
+func writeRows(ctx context.Context, w io.Writer, format writeFormat, query string, params []any) error {
+	var (
+		rename RenameColumns
+		qp     = make([]any, 0, len(params))
+	)
+	for _, p := range params {
+		if r, ok := p.(RenameColumns); ok {
+			rename = r
+			continue
+		}
+		qp = append(qp, p)
+	}
+
+	rows, err := Query(ctx, query, qp...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	var (
+		dialect = SQLDialect(ctx)
+		kinds   = make([]writeKind, len(types))
+	)
+	for i, t := range types {
+		kinds[i] = writeKindOf(dialect, t.DatabaseTypeName())
+	}
+	for i, c := range cols {
+		if r, ok := rename[c]; ok {
+			cols[i] = r
+		}
+	}
+
+	var (
+		bw   = bufio.NewWriter(w)
+		cw   *csv.Writer
+		keys = make([][]byte, len(cols))
+		vals = make([]any, len(cols))
+		ptrs = make([]any, len(cols))
+		rec  []string
+	)
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	switch format {
+	case writeCSV:
+		cw, rec = csv.NewWriter(bw), make([]string, len(cols))
+		err := cw.Write(cols)
+		if err != nil {
+			return err
+		}
+	default:
+		for i, c := range cols {
+			keys[i], err = json.Marshal(c)
+			if err != nil {
+				return err
+			}
+		}
+		if format == writeJSON {
+			bw.WriteByte('[')
+		}
+	}
+
+	first := true
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		err := rows.r.Scan(ptrs...)
+		if err != nil {
+			return err
+		}
+
+		if format == writeCSV {
+			for i, v := range vals {
+				rec[i] = writeString(v, kinds[i] == writeBinary)
+			}
+			err := cw.Write(rec)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		if format == writeJSON {
+			if !first {
+				bw.WriteByte(',')
+			}
+			bw.WriteString("\n\t")
+		}
+		bw.WriteByte('{')
+		for i, v := range vals {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			bw.Write(keys[i])
+			bw.WriteByte(':')
+			j, err := writeJSONValue(v, kinds[i])
+			if err != nil {
+				return fmt.Errorf("column %q: %w", cols[i], err)
+			}
+			bw.Write(j)
+		}
+		bw.WriteByte('}')
+		if format == writeNDJSON {
+			bw.WriteByte('\n')
+		}
+		first = false
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	switch format {
+	case writeCSV:
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return err
+		}
+	case writeJSON:
+		if !first {
+			bw.WriteByte('\n')
+		}
+		bw.WriteString("]\n")
+	}
+	return bw.Flush()
+}
+
+type writeKind uint8
+
+const (
+	writeOther writeKind = iota
+	writeBinary
+	writeNumber
+	writeBool
+	writeTime
+)
+
+// writeKindOf gets how to write values from a column with the database type
+// typ.
+func writeKindOf(dialect Dialect, typ string) writeKind {
+	base, _, _ := strings.Cut(strings.ToLower(typ), "(")
+	switch goType(dialect, typ) {
+	case "[]byte":
+		return writeBinary
+	case "int64", "float64":
+		return writeNumber
+	case "bool":
+		return writeBool
+	case "time.Time":
+		return writeTime
+	}
+	if base == "numeric" || base == "decimal" {
+		return writeNumber
+	}
+	return writeOther
+}
+
+var reJSONNumber = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+func writeJSONValue(v any, kind writeKind) ([]byte, error) {
+	if b, ok := v.([]byte); ok && kind != writeBinary {
+		v = string(b)
+	}
+
+	switch vv := v.(type) {
+	case nil:
+		return []byte("null"), nil
+	case []byte:
+		return json.Marshal(vv) // base64
+	case time.Time:
+		return json.Marshal(vv.Format(time.RFC3339Nano))
+	case int64:
+		if kind == writeBool {
+			return json.Marshal(vv != 0)
+		}
+	case string:
+		switch kind {
+		case writeNumber:
+			if reJSONNumber.MatchString(vv) {
+				return []byte(vv), nil
+			}
+		case writeBool:
+			if b, err := strconv.ParseBool(vv); err == nil {
+				return json.Marshal(b)
+			}
+		case writeTime:
+			if t, err := time.Parse("2006-01-02 15:04:05.999999999", vv); err == nil {
+				return json.Marshal(t.Format(time.RFC3339Nano))
+			}
+		}
+	}
+	return json.Marshal(v)
+}
+
+func writeString(v any, binary bool) string {
+	switch vv := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		if binary {
+			return base64.StdEncoding.EncodeToString(vv)
+		}
+		return string(vv)
+	case string:
+		return vv
+	case time.Time:
+		return vv.Format(time.RFC3339Nano)
+	case int64:
+		return strconv.FormatInt(vv, 10)
+	case float64:
+		return strconv.FormatFloat(vv, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(vv)
+	default:
+		return fmt.Sprint(vv)
+	}
+}
@@ -0,0 +1,49 @@
+package zdb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWriteJSONValue(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		typ     string
+		v       any
+		want    string
+	}{
+		// MariaDB returns most values as []byte.
+		{DialectMariaDB, "INT", []byte("42"), `42`},
+		{DialectMariaDB, "UNSIGNED BIGINT", []byte("42"), `42`},
+		{DialectMariaDB, "DECIMAL", []byte("-1.50"), `-1.50`},
+		{DialectMariaDB, "DOUBLE", []byte("1e+30"), `1e+30`},
+		{DialectMariaDB, "DATETIME", []byte("2020-06-18 12:13:14"), `"2020-06-18T12:13:14Z"`},
+		{DialectMariaDB, "DATE", []byte("2020-06-18"), `"2020-06-18"`},
+		{DialectMariaDB, "VARCHAR", []byte("42"), `"42"`},
+		{DialectMariaDB, "BLOB", []byte{0xff, 0x00}, `"/wA="`},
+		{DialectMariaDB, "INT", nil, `null`},
+
+		{DialectPostgreSQL, "NUMERIC", "3.14", `3.14`},
+		{DialectPostgreSQL, "NUMERIC", "NaN", `"NaN"`},
+		{DialectPostgreSQL, "INT8", int64(42), `42`},
+		{DialectPostgreSQL, "BOOL", true, `true`},
+		{DialectPostgreSQL, "TIMESTAMPTZ", time.Date(2020, 6, 18, 12, 13, 14, 0, time.UTC), `"2020-06-18T12:13:14Z"`},
+		{DialectPostgreSQL, "TEXT", "x", `"x"`},
+
+		{DialectSQLite, "BOOLEAN", int64(1), `true`},
+		{DialectSQLite, "INTEGER", "not a number", `"not a number"`},
+		{DialectSQLite, "", float64(1.5), `1.5`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			have, err := writeJSONValue(tt.v, writeKindOf(tt.dialect, tt.typ))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(have) != tt.want {
+				t.Errorf("\nhave: %s\nwant: %s", have, tt.want)
+			}
+		})
+	}
+}
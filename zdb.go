@@ -7,7 +7,9 @@
 //
 // Everything between {{:name ..}} is parsed as a conditional; for example
 // {{:foo query}} will only be added if "foo" from params is true or not a zero
-// type. Conditionals only work with named parameters.
+// type. Use {{:foo query || else}} to add text if it's false, and {{:foo! query}}
+// to invert the match. Conditionals can be nested; for positional parameters the
+// name is the parameter number: {{:1 and x = ?}}.
 //
 // If the query starts with "load:" then it's loaded from the filesystem or
 // embedded files; see Load() for details.
@@ -37,6 +39,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"reflect"
 
 	"zgo.at/zdb/drivers"
 	"zgo.at/zdb/internal/sqlx"
@@ -77,6 +80,11 @@ type (
 	// Generally speaking you rarely want to use this, except in some rare cases
 	// where 1) parameters won't work, and 2) you're really sure this value is
 	// safe.
+	//
+	// This can be used as both a named and positional parameter (with ? or $n
+	// placeholders):
+	//
+	//	zdb.Select(ctx, &rows, `select * from t order by ?`, zdb.SQL("name desc"))
 	SQL string
 
 	// Dialect is an SQL dialect. This can be represented by multiple drivers;
@@ -144,6 +152,23 @@ func Info(ctx context.Context) (ServerInfo, error) {
 //	zdb.QueryGet(ctx, "load:select-x", &foo, zdb.P{
 //	    "param": "foo",
 //	})
+//
+// Files ending with ".gotxt" are run through text/template when used with
+// "load:"; all template functions from [Template] are available, the
+// parameters are available as {{.name}}, and files in "db/query/_partials/"
+// can be included with {{template "name" .}}, where name is the filename
+// without ".gotxt". The {{:name ..}} conditionals are written as {%:name ..%}
+// in templates:
+//
+//	select * from hits
+//	where {{template "where-site" .}}
+//	    {%:path and path = :path%}
+//
+// Use "//go:embed all:db" to embed the _partials directory, as go:embed skips
+// directories starting with "_" by default.
+//
+// The returned bool reports if the query is a template; Load() doesn't run the
+// template.
 func Load(db DB, name string) (string, bool, error) {
 	return loadImpl(db, name)
 }
@@ -208,11 +233,35 @@ func InsertID[T cmp.Ordered](ctx context.Context, idColumn, query string, params
 //   - []any
 //
 // Returns nil (and no error) if there are no rows.
+//
+// Struct fields that are a struct or a slice of structs can use the "prefix"
+// option to load data from joined tables; columns starting with the prefix are
+// mapped to the fields of that struct. For slices the rows are grouped by the
+// field with the ",id" option, so you can load a one-to-many relation in a
+// single query:
+//
+//	type User struct {
+//	    ID     int     `db:"user_id,id"`
+//	    Orders []Order `db:"orders,prefix=o_"`
+//	}
+//
+//	zdb.Select(ctx, &users, `select u.user_id, o.order_id as o_order_id, o.total as o_total
+//	    from users u left join orders o using (user_id)`)
+//
+// Slice elements where all columns are NULL (from a LEFT JOIN) are skipped, and
+// duplicates are skipped if the element type has an ",id" field.
+//
+// Fields with the ",json" option are unmarshalled with encoding/json; this
+// works for any column type that returns text, such as jsonb, json, and
+// varchar. NULL sets the field to the zero value.
 func Select(ctx context.Context, dest any, query string, params ...any) error {
 	return selectImpl(ctx, MustGetDB(ctx), dest, query, params...)
 }
 
 // Get one row, returning sql.ErrNoRows if there are no rows.
+//
+// Slices with the "prefix" option are loaded from all rows that have the same
+// ",id" as the first row, as described in [Select].
 func Get(ctx context.Context, dest any, query string, params ...any) error {
 	return getImpl(ctx, MustGetDB(ctx), dest, query, params...)
 }
@@ -241,10 +290,13 @@ func (r *Rows) ColumnTypes() ([]*sql.ColumnType, error) { return r.r.ColumnTypes
 func (r *Rows) StructScan(dest any) error               { return r.r.StructScan(dest) }
 func (r *Rows) Scan(dest ...any) error {
 	if len(dest) > 1 {
-		return r.r.Scan(dest...)
+		return r.r.Scan(sqlx.ScanDests(dest)...)
 	}
 
 	d := dest[0]
+	if s := sqlx.ScanDest(d); reflect.TypeOf(s) != reflect.TypeOf(d) {
+		return r.r.Scan(s)
+	}
 	if m, ok := d.(*map[string]any); ok {
 		if *m == nil {
 			*m = make(map[string]any)
@@ -338,6 +390,38 @@ func ErrMissingField(err error) bool {
 	return errors.As(err, &m)
 }
 
+// ErrMissingColumn reports if this error is because a struct field has no
+// column, with [StrictFields].
+func ErrMissingColumn(err error) bool {
+	var m *sqlx.ErrMissingColumn
+	return errors.As(err, &m)
+}
+
+// Strict scanning mode.
+//
+// By default columns without a struct field are scanned as much as possible
+// and an error for which [ErrMissingField] returns true is returned, and struct
+// fields without a column are left alone.
+//
+// In strict mode an error is returned before anything is scanned. Strict mode
+// can be enabled for all queries with [ConnectOptions], or for a single query
+// by passing it as a parameter to Get(), Select(), or Query():
+//
+//	err := zdb.Select(ctx, &rows, `select * from t`, zdb.StrictAll)
+//
+// A parameter overrides the mode from ConnectOptions; use Strict(0) to disable
+// it for a query.
+//
+// StrictFields doesn't check the fields of slices with the "prefix" option.
+type Strict uint8
+
+const (
+	StrictColumns Strict = 1 << iota // Error on columns without a struct field.
+	StrictFields                     // Error on struct fields without a column.
+
+	StrictAll = StrictColumns | StrictFields
+)
+
 // SQLDialect gets the SQL dialect.
 func SQLDialect(ctx context.Context) Dialect {
 	return MustGetDB(ctx).SQLDialect()
@@ -359,5 +443,8 @@ func DriverConnection(db DB) any {
 	if x, ok := db.(*zTX); ok {
 		return x.parent.driverConn
 	}
+	if x, ok := db.(*zConn); ok {
+		return x.parent.driverConn
+	}
 	return nil
 }
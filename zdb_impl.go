@@ -56,6 +56,10 @@ type zDB struct {
 	dialect       Dialect
 	queryFS       fs.FS
 	connectString string
+	strict        Strict
+	lint          bool
+	quote         byte      // Character to quote identifiers with.
+	tpl           *tplCache // Parsed .gotxt queries.
 }
 
 func (db zDB) queryFiles() fs.FS              { return db.queryFS }
@@ -63,6 +67,10 @@ func (db zDB) rebind(query string) string     { return db.db.Rebind(query) }
 func (db zDB) ping(ctx context.Context) error { return db.db.PingContext(ctx) }
 func (db zDB) driverName() string             { return db.db.DriverName() }
 func (db zDB) connect() string                { return db.connectString }
+func (db zDB) strictMode() Strict             { return db.strict }
+func (db zDB) lintParams() bool               { return db.lint }
+func (db zDB) identQuote() byte               { return db.quote }
+func (db zDB) queryTemplates() *tplCache      { return db.tpl }
 
 func (db zDB) DBSQL() (*sql.DB, *sql.Tx)                    { return db.db.DB, nil }
 func (db zDB) SQLDialect() Dialect                          { return db.dialect }
@@ -128,10 +136,14 @@ type zTX struct {
 }
 
 func (db zTX) queryFiles() fs.FS              { return db.parent.queryFiles() }
+func (db zTX) queryTemplates() *tplCache      { return db.parent.queryTemplates() }
 func (db zTX) rebind(query string) string     { return db.parent.rebind(query) }
 func (db zTX) ping(ctx context.Context) error { return db.parent.ping(ctx) }
 func (db zTX) driverName() string             { return db.parent.driverName() }
 func (db zTX) connect() string                { return db.parent.connect() }
+func (db zTX) strictMode() Strict             { return db.parent.strictMode() }
+func (db zTX) lintParams() bool               { return db.parent.lintParams() }
+func (db zTX) identQuote() byte               { return db.parent.identQuote() }
 
 func (db zTX) DBSQL() (*sql.DB, *sql.Tx)                    { p, _ := db.parent.DBSQL(); return p, db.db.Tx }
 func (db zTX) SQLDialect() Dialect                          { return db.parent.dialect }
@@ -188,6 +200,73 @@ func (db zTX) QueryxContext(ctx context.Context, query string, params ...any) (*
 	return db.db.QueryxContext(ctx, query, params...)
 }
 
+// zConn is a zDB that runs all queries on a single connection, rather than
+// picking a connection from the pool for every query. This is needed when
+// there's session state, such as a transaction started with "begin" or a
+// "set search_path".
+type zConn struct {
+	db     *sqlx.Conn
+	parent *zDB
+}
+
+func (db zConn) queryFiles() fs.FS              { return db.parent.queryFiles() }
+func (db zConn) queryTemplates() *tplCache      { return db.parent.queryTemplates() }
+func (db zConn) rebind(query string) string     { return db.parent.rebind(query) }
+func (db zConn) ping(ctx context.Context) error { return db.db.PingContext(ctx) }
+func (db zConn) driverName() string             { return db.parent.driverName() }
+func (db zConn) connect() string                { return db.parent.connect() }
+func (db zConn) strictMode() Strict             { return db.parent.strictMode() }
+func (db zConn) lintParams() bool               { return db.parent.lintParams() }
+func (db zConn) identQuote() byte               { return db.parent.identQuote() }
+
+func (db zConn) DBSQL() (*sql.DB, *sql.Tx)                    { return db.parent.DBSQL() }
+func (db zConn) SQLDialect() Dialect                          { return db.parent.dialect }
+func (db zConn) Info(ctx context.Context) (ServerInfo, error) { return infoImpl(ctx, db) }
+
+// Close returns the connection to the pool; it doesn't close the parent DB.
+func (db zConn) Close() error { return db.db.Close() }
+
+func (db zConn) Exec(ctx context.Context, query string, params ...any) error {
+	return execImpl(ctx, db, query, params...)
+}
+func (db zConn) NumRows(ctx context.Context, query string, params ...any) (int64, error) {
+	return numRowsImpl(ctx, db, query, params...)
+}
+func (db zConn) InsertID(ctx context.Context, idColumn, query string, params ...any) (int64, error) {
+	return insertIDImpl[int64](ctx, db, idColumn, query, params...)
+}
+func (db zConn) Get(ctx context.Context, dest any, query string, params ...any) error {
+	return getImpl(ctx, db, dest, query, params...)
+}
+func (db zConn) Select(ctx context.Context, dest any, query string, params ...any) error {
+	return selectImpl(ctx, db, dest, query, params...)
+}
+func (db zConn) Query(ctx context.Context, query string, params ...any) (*Rows, error) {
+	return queryImpl(ctx, db, query, params...)
+}
+
+func (db zConn) TX(ctx context.Context, fn func(context.Context) error) error {
+	return txImpl(ctx, db, fn)
+}
+func (db zConn) Begin(ctx context.Context, opts ...beginOpt) (context.Context, DB, error) {
+	return beginImpl(ctx, &db, opts...)
+}
+func (db zConn) Rollback() error { return errors.New("cannot rollback, as this is not a transaction") }
+func (db zConn) Commit() error   { return errors.New("cannot commit, as this is not a transaction") }
+
+func (db zConn) ExecContext(ctx context.Context, query string, params ...any) (sql.Result, error) {
+	return db.db.ExecContext(ctx, query, params...)
+}
+func (db zConn) GetContext(ctx context.Context, dest any, query string, params ...any) error {
+	return db.db.GetContext(ctx, dest, query, params...)
+}
+func (db zConn) SelectContext(ctx context.Context, dest any, query string, params ...any) error {
+	return db.db.SelectContext(ctx, dest, query, params...)
+}
+func (db zConn) QueryxContext(ctx context.Context, query string, params ...any) (*sqlx.Rows, error) {
+	return db.db.QueryxContext(ctx, query, params...)
+}
+
 // Actual implementations
 // ----------------------
 
@@ -251,9 +330,6 @@ func infoImpl(ctx context.Context, db DB) (ServerInfo, error) {
 // TODO: this could be cached, but if the FS is an os.DirFS then it may have
 // changes on the filesystem (being able to change queries w/o recompile is
 // nice).
-//
-// TODO: implement .gotxt support here too? The {{ .. }} from our own
-// mini-template syntax will clash though.
 func loadImpl(db DB, name string) (string, bool, error) {
 	fsys := db.(interface{ queryFiles() fs.FS }).queryFiles()
 	if fsys == nil {
@@ -306,15 +382,43 @@ func beginImpl(ctx context.Context, db DB, opts ...beginOpt) (context.Context, D
 		o(txopt)
 	}
 
-	tx, err := db.(*zDB).db.BeginTxx(ctx, txopt)
+	var (
+		tx     *sqlx.Tx
+		parent *zDB
+		err    error
+	)
+	if c, ok := Unwrap(db).(*zConn); ok {
+		tx, err = c.db.BeginTxx(ctx, txopt)
+		parent = c.parent
+	} else {
+		parent = Unwrap(db).(*zDB)
+		tx, err = parent.db.BeginTxx(ctx, txopt)
+	}
 	if err != nil {
 		return nil, nil, fmt.Errorf("zdb.Begin: %w", err)
 	}
 
-	ztx := &zTX{db: tx, parent: Unwrap(db).(*zDB)}
+	ztx := &zTX{db: tx, parent: parent}
 	return WithDB(ctx, ztx), ztx, nil
 }
 
+// connImpl returns a copy of db that runs all queries on a single connection.
+// A transaction is already bound to a single connection, and is returned as-is.
+func connImpl(ctx context.Context, db DB) (DB, error) {
+	switch d := Unwrap(db).(type) {
+	case *zTX, *zConn:
+		return db, nil
+	case *zDB:
+		c, err := d.db.Connx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return &zConn{db: c, parent: d}, nil
+	default:
+		return nil, fmt.Errorf("unknown DB type %T", db)
+	}
+}
+
 func txImpl(ctx context.Context, db DB, fn func(context.Context) error) error {
 	txctx, tx, err := db.Begin(ctx)
 	if err == ErrTransactionStarted {
@@ -392,12 +496,31 @@ func insertIDImpl[T cmp.Ordered](ctx context.Context, db DB, idColumn, query str
 	return id[len(id)-1], nil
 }
 
+// withStrict sets the strict scanning mode from a Strict parameter, or the
+// default from ConnectOptions.
+func withStrict(ctx context.Context, db DB, params []any) context.Context {
+	var mode Strict
+	if s, ok := Unwrap(db).(interface{ strictMode() Strict }); ok {
+		mode = s.strictMode()
+	}
+	for _, p := range params {
+		if s, ok := p.(Strict); ok {
+			mode = s
+		}
+	}
+	if mode == 0 {
+		return ctx
+	}
+	return sqlx.WithStrict(ctx, sqlx.Strict(mode))
+}
+
 func selectImpl(ctx context.Context, db DB, dest any, query string, params ...any) error {
+	sctx := withStrict(ctx, db, params)
 	query, params, err := prepareImpl(ctx, db, query, params...)
 	if err != nil {
 		return fmt.Errorf("zdb.Select: %w", err)
 	}
-	err = db.(dbImpl).SelectContext(ctx, dest, query, params...)
+	err = db.(dbImpl).SelectContext(sctx, dest, query, params...)
 	if err != nil {
 		return fmt.Errorf("zdb.Select: %w", err)
 	}
@@ -405,11 +528,12 @@ func selectImpl(ctx context.Context, db DB, dest any, query string, params ...an
 }
 
 func getImpl(ctx context.Context, db DB, dest any, query string, params ...any) error {
+	sctx := withStrict(ctx, db, params)
 	query, params, err := prepareImpl(ctx, db, query, params...)
 	if err != nil {
 		return fmt.Errorf("zdb.Get: %w", err)
 	}
-	err = db.(dbImpl).GetContext(ctx, dest, query, params...)
+	err = db.(dbImpl).GetContext(sctx, dest, query, params...)
 	if err != nil {
 		return fmt.Errorf("zdb.Get: %w", err)
 	}
@@ -417,11 +541,12 @@ func getImpl(ctx context.Context, db DB, dest any, query string, params ...any)
 }
 
 func queryImpl(ctx context.Context, db DB, query string, params ...any) (*Rows, error) {
+	sctx := withStrict(ctx, db, params)
 	query, params, err := prepareImpl(ctx, db, query, params...)
 	if err != nil {
 		return nil, fmt.Errorf("zdb.Query: %w", err)
 	}
-	r, err := db.(dbImpl).QueryxContext(ctx, query, params...)
+	r, err := db.(dbImpl).QueryxContext(sctx, query, params...)
 	if err != nil {
 		return nil, fmt.Errorf("zdb.Query: %w", err)
 	}
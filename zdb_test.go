@@ -3,4 +3,5 @@ package zdb
 var (
 	_ DB = zDB{}
 	_ DB = zTX{}
+	_ DB = zConn{}
 )